@@ -0,0 +1,200 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CEPCache is a cache of ConsultaCEP results. The key is the filtered CEP
+// (see FilterCEP). Implementations must be safe for concurrent use.
+type CEPCache interface {
+	Get(cep string) (*CEPResult, bool)
+	Set(cep string, r *CEPResult)
+}
+
+// MemCEPCache is an in-memory CEPCache with expiration.
+type MemCEPCache struct {
+	ttl     time.Duration
+	l       sync.Mutex
+	entries map[string]memCEPCacheEntry
+}
+
+type memCEPCacheEntry struct {
+	r       CEPResult
+	expires time.Time
+}
+
+// NewMemCEPCache creates an in-memory CEPCache. Entries expire after ttl;
+// if ttl <= 0, entries never expire.
+func NewMemCEPCache(ttl time.Duration) *MemCEPCache {
+	return &MemCEPCache{
+		ttl:     ttl,
+		entries: make(map[string]memCEPCacheEntry),
+	}
+}
+
+// Get returns a copy of the cached result.
+func (c *MemCEPCache) Get(cep string) (*CEPResult, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	e, ok := c.entries[cep]
+	if !ok {
+		return nil, false
+	}
+	if c.ttl > 0 && time.Now().After(e.expires) {
+		delete(c.entries, cep)
+		return nil, false
+	}
+	r := e.r
+	return &r, true
+}
+
+// Set stores a copy of r.
+func (c *MemCEPCache) Set(cep string, r *CEPResult) {
+	if r == nil {
+		return
+	}
+	c.l.Lock()
+	defer c.l.Unlock()
+	c.entries[cep] = memCEPCacheEntry{
+		r:       *r,
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// Len returns the number of cached entries (including expired entries that
+// were not yet removed).
+func (c *MemCEPCache) Len() int {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return len(c.entries)
+}
+
+// FreteCache is a cache of CalcularFrete responses (see Client.FreteCache).
+// The key is an opaque hash of the normalized request parameters.
+// Implementations must be safe for concurrent use.
+type FreteCache interface {
+	Get(key string) (*FreteResponse, bool)
+	Set(key string, r *FreteResponse)
+}
+
+// MemFreteCache is an in-memory FreteCache with expiration.
+type MemFreteCache struct {
+	ttl     time.Duration
+	l       sync.Mutex
+	entries map[string]memFreteCacheEntry
+}
+
+type memFreteCacheEntry struct {
+	r       *FreteResponse
+	expires time.Time
+}
+
+// NewMemFreteCache creates an in-memory FreteCache. Entries expire after
+// ttl; if ttl <= 0, entries never expire.
+func NewMemFreteCache(ttl time.Duration) *MemFreteCache {
+	return &MemFreteCache{
+		ttl:     ttl,
+		entries: make(map[string]memFreteCacheEntry),
+	}
+}
+
+// Get returns a copy of the cached response.
+func (c *MemFreteCache) Get(key string) (*FreteResponse, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.ttl > 0 && time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.r.clone(), true
+}
+
+// Set stores a copy of r.
+func (c *MemFreteCache) Set(key string, r *FreteResponse) {
+	if r == nil {
+		return
+	}
+	c.l.Lock()
+	defer c.l.Unlock()
+	c.entries[key] = memFreteCacheEntry{
+		r:       r.clone(),
+		expires: time.Now().Add(c.ttl),
+	}
+}
+
+// Len returns the number of cached entries (including expired entries that
+// were not yet removed).
+func (c *MemFreteCache) Len() int {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return len(c.entries)
+}
+
+// freteCacheKey returns the cache key of req: a hash of its parameters
+// (see FreteRequest.Values), with the CEPs filtered, the services sorted
+// and the Client credentials applied. The hash keeps the password out of
+// the key.
+func (c *Client) freteCacheKey(req *FreteRequest) string {
+	r := req.Clone()
+	r.CepOrigem = FilterCEP(r.CepOrigem)
+	r.CepDestino = FilterCEP(r.CepDestino)
+	if r.CdEmpresa == "" {
+		r.CdEmpresa, r.DsSenha = c.CdEmpresa, c.DsSenha
+	}
+	sort.Slice(r.Servicos, func(i, j int) bool { return r.Servicos[i] < r.Servicos[j] })
+	v := r.Values()
+	// per-service formats (Formatos) are not part of Values
+	formatos := make([]string, 0, len(r.Servicos))
+	for _, svc := range r.Servicos {
+		formatos = append(formatos, string(svc)+":"+strconv.Itoa(int(r.FormatoServico(svc))))
+	}
+	v.Set("formatos", strings.Join(formatos, ","))
+	sum := sha256.Sum256([]byte(v.Encode()))
+	return hex.EncodeToString(sum[:])
+}
+
+// cacheableFrete reports whether resp can be stored in the FreteCache: only
+// responses from the Correios without transient errors are stored. Services
+// without coverage (SemCobertura) and code 010 are final answers and don't
+// prevent caching.
+func cacheableFrete(resp *FreteResponse) bool {
+	if resp == nil || resp.Origem != OrigemCorreios || len(resp.Servicos) == 0 {
+		return false
+	}
+	for _, v := range resp.Servicos {
+		if v.ParseErr != nil || v.transitorio() || (v.Erro != nil && v.Erro.Codigo == ErrIndeterminado) {
+			return false
+		}
+	}
+	return true
+}
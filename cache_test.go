@@ -0,0 +1,160 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gabstv/correios"
+	"github.com/gabstv/correios/correiostest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCEPCache(t *testing.T) {
+	n := 0
+	defer withCEPHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.Write([]byte(cepJSON))
+	})()
+	cache := correios.NewMemCEPCache(time.Hour)
+	c := &correios.Client{CEPCache: cache}
+	r, err := c.ConsultaCEP(context.Background(), "13056-535")
+	assert.NoError(t, err)
+	r.Cidade = "alterado"
+	r, err = c.ConsultaCEP(context.Background(), "13056535")
+	assert.NoError(t, err)
+	assert.Equal(t, "Campinas", r.Cidade)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, cache.Len())
+
+	expired := correios.NewMemCEPCache(time.Nanosecond)
+	expired.Set("13056535", r)
+	time.Sleep(time.Millisecond)
+	_, ok := expired.Get("13056535")
+	assert.False(t, ok)
+}
+
+func TestFreteCache(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	cache := correios.NewMemFreteCache(time.Hour)
+	c := srv.Client()
+	c.FreteCache = cache
+	r := correios.NewFreteRequest("01243-000", "65299970").SetServicos(correios.SvcSEDEXVarejo, correios.SvcPACVarejo)
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, correios.OrigemCorreios, resp.Origem)
+	assert.Len(t, srv.Requests(), 2)
+	assert.Equal(t, 1, cache.Len())
+
+	// same parameters, different CEP format and service order
+	r2 := correios.NewFreteRequest("01243000", "65299-970").SetServicos(correios.SvcPACVarejo, correios.SvcSEDEXVarejo)
+	resp2, err := c.CalcularFrete(context.Background(), r2)
+	assert.NoError(t, err)
+	assert.Equal(t, correios.OrigemCache, resp2.Origem)
+	assert.Equal(t, resp.Servicos, resp2.Servicos)
+	assert.Equal(t, resp.Timestamp, resp2.Timestamp)
+	assert.Len(t, srv.Requests(), 2)
+
+	// bypass
+	r2.IgnorarCache = true
+	resp2, err = c.CalcularFrete(context.Background(), r2)
+	assert.NoError(t, err)
+	assert.Equal(t, correios.OrigemCorreios, resp2.Origem)
+	assert.Len(t, srv.Requests(), 4)
+
+	// transient errors are not cached
+	srv.SetErro(correios.SvcSEDEX10Varejo, correios.ErrSistemaIndisponivel)
+	r3 := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEX10Varejo)
+	_, err = c.CalcularFrete(context.Background(), r3)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cache.Len())
+
+	// services without coverage are final answers and are cached
+	srv.SetErro(correios.SvcPACVarejo, correios.ErrServicoIndisponivelTrecho)
+	srv.SetServico(correiostest.Servico{
+		Codigo:  correios.SvcSEDEXVarejo,
+		Valor:   "0,00",
+		Erro:    correios.ErrLocalidadeDestino,
+		MsgErro: "Localidade de destino não abrangida pelo serviço informado",
+	})
+	r4 := correios.NewFreteRequest("01243000", "69900000").SetServicos(correios.SvcSEDEXVarejo, correios.SvcPACVarejo)
+	_, err = c.CalcularFrete(context.Background(), r4)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cache.Len())
+	n := len(srv.Requests())
+	resp4, err := c.CalcularFrete(context.Background(), r4)
+	assert.NoError(t, err)
+	assert.Equal(t, correios.OrigemCache, resp4.Origem)
+	assert.True(t, resp4.Servicos[correios.SvcSEDEXVarejo].SemCobertura())
+	assert.Len(t, srv.Requests(), n)
+
+	// expired entries
+	cache = correios.NewMemFreteCache(time.Nanosecond)
+	cache.Set("k", resp)
+	time.Sleep(time.Millisecond)
+	_, ok := cache.Get("k")
+	assert.False(t, ok)
+}
+
+type metricasContador struct {
+	fretes int32
+}
+
+func (m *metricasContador) IncFrete(err error)                                 { atomic.AddInt32(&m.fretes, 1) }
+func (m *metricasContador) IncServico(correios.TipoServico, correios.TipoErro) {}
+
+func TestFreteCacheAjusteValorDeclarado(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	m := &metricasContador{}
+	c := srv.Client()
+	c.FreteCache = correios.NewMemFreteCache(time.Hour)
+	c.ClampValorDeclarado = true
+	c.Metricas = m
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resp, err := c.CalcularFrete(context.Background(), r.Clone().SetValorDeclaradoFloat(15000))
+	assert.NoError(t, err)
+	if assert.NotNil(t, resp.AjusteValorDeclarado) {
+		assert.Equal(t, "15000", resp.AjusteValorDeclarado.Original.String())
+	}
+
+	// same key (clamped to 10000), but the adjustment is the current one
+	resp, err = c.CalcularFrete(context.Background(), r.Clone().SetValorDeclaradoFloat(12000))
+	assert.NoError(t, err)
+	assert.Equal(t, correios.OrigemCache, resp.Origem)
+	if assert.NotNil(t, resp.AjusteValorDeclarado) {
+		assert.Equal(t, "12000", resp.AjusteValorDeclarado.Original.String())
+		assert.Equal(t, "10000", resp.AjusteValorDeclarado.Ajustado.String())
+	}
+	resp, err = c.CalcularFrete(context.Background(), r.Clone().SetValorDeclaradoFloat(10000))
+	assert.NoError(t, err)
+	assert.Equal(t, correios.OrigemCache, resp.Origem)
+	assert.Nil(t, resp.AjusteValorDeclarado)
+	assert.Len(t, srv.Requests(), 1)
+
+	// cache hits are also measured
+	assert.Equal(t, int32(3), atomic.LoadInt32(&m.fretes))
+}
@@ -28,6 +28,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 var (
@@ -39,67 +42,403 @@ var (
 
 // CEPResult is the result of a ConsultaCEP request.
 type CEPResult struct {
-	CEP        string `json:"cep"`
-	UF         string `json:"uf"`
-	Cidade     string `json:"cidade"`
-	Bairro     string `json:"bairro"`
-	Logradouro string `json:"logradouro"`
+	CEP         string `json:"cep"`
+	UF          string `json:"uf"`
+	Cidade      string `json:"cidade"`
+	Bairro      string `json:"bairro"`
+	Logradouro  string `json:"logradouro"`
+	NomeUnidade string `json:"nomeUnidade"`
+	Situacao    string `json:"situacao"`
+	TipoCep     string `json:"tipoCep"`
+	// CaixaPostal is set when the Correios returned PO box (caixa postal)
+	// ranges for the CEP. See EhCaixaPostal.
+	CaixaPostal bool `json:"caixaPostal,omitempty"`
+}
+
+// CEP types (TipoCep) used exclusively by PO boxes: operational units
+// (agências, whose CEP is used by their caixas postais) and community PO
+// boxes (Caixa Postal Comunitária).
+const (
+	tipoCepUnidadeOperacional = "4"
+	tipoCepCaixaPostal        = "5"
+)
+
+// EhCaixaPostal reports whether the CEP belongs to a PO box (caixa postal),
+// either by its TipoCep or because the Correios returned PO box ranges.
+// Services that deliver to the door can't be used with these CEPs.
+func (r *CEPResult) EhCaixaPostal() bool {
+	switch r.TipoCep {
+	case tipoCepUnidadeOperacional, tipoCepCaixaPostal:
+		return true
+	}
+	return r.CaixaPostal
 }
 
 // RawCEPResult is the raw data of a ConsultaCEP request.
 type RawCEPResult struct {
-	Erro     bool   `json:"erro"`
-	Mensagem string `json:"mensagem"`
-	Total    int    `json:"total"`
-	Dados    []struct {
-		Uf                       string        `json:"uf"`
-		Localidade               string        `json:"localidade"`
-		LocNoSem                 string        `json:"locNoSem"`
-		LocNu                    string        `json:"locNu"`
-		LocalidadeSubordinada    string        `json:"localidadeSubordinada"`
-		LogradouroDNEC           string        `json:"logradouroDNEC"`
-		LogradouroTextoAdicional string        `json:"logradouroTextoAdicional"`
-		LogradouroTexto          string        `json:"logradouroTexto"`
-		Bairro                   string        `json:"bairro"`
-		BaiNu                    string        `json:"baiNu"`
-		NomeUnidade              string        `json:"nomeUnidade"`
-		Cep                      string        `json:"cep"`
-		TipoCep                  string        `json:"tipoCep"`
-		NumeroLocalidade         string        `json:"numeroLocalidade"`
-		Situacao                 string        `json:"situacao"`
-		FaixasCaixaPostal        []interface{} `json:"faixasCaixaPostal"`
-		FaixasCep                []interface{} `json:"faixasCep"`
-	} `json:"dados"`
+	Erro     bool         `json:"erro"`
+	Mensagem string       `json:"mensagem"`
+	Total    int          `json:"total"`
+	Dados    []RawCEPDado `json:"dados"`
+}
+
+// RawCEPDado is a single entry of RawCEPResult.
+type RawCEPDado struct {
+	Uf                       string        `json:"uf"`
+	Localidade               string        `json:"localidade"`
+	LocNoSem                 string        `json:"locNoSem"`
+	LocNu                    string        `json:"locNu"`
+	LocalidadeSubordinada    string        `json:"localidadeSubordinada"`
+	LogradouroDNEC           string        `json:"logradouroDNEC"`
+	LogradouroTextoAdicional string        `json:"logradouroTextoAdicional"`
+	LogradouroTexto          string        `json:"logradouroTexto"`
+	Bairro                   string        `json:"bairro"`
+	BaiNu                    string        `json:"baiNu"`
+	NomeUnidade              string        `json:"nomeUnidade"`
+	Cep                      string        `json:"cep"`
+	TipoCep                  string        `json:"tipoCep"`
+	NumeroLocalidade         string        `json:"numeroLocalidade"`
+	Situacao                 string        `json:"situacao"`
+	FaixasCaixaPostal        []interface{} `json:"faixasCaixaPostal"`
+	FaixasCep                []interface{} `json:"faixasCep"`
+}
+
+// CEPResult converts the raw entry to a *CEPResult.
+func (d RawCEPDado) CEPResult() *CEPResult {
+	result := &CEPResult{
+		CEP:         d.Cep,
+		UF:          d.Uf,
+		Cidade:      d.Localidade,
+		Bairro:      d.Bairro,
+		NomeUnidade: d.NomeUnidade,
+		Situacao:    d.Situacao,
+		TipoCep:     d.TipoCep,
+		CaixaPostal: len(d.FaixasCaixaPostal) > 0,
+	}
+	if d.LogradouroDNEC != "" {
+		result.Logradouro = d.LogradouroDNEC
+	} else if d.LogradouroTexto != "" {
+		result.Logradouro = d.LogradouroTexto
+	}
+	return result
+}
+
+// CEPResultDetalhado is a CEPResult with the additional data returned by
+// the Correios, such as the PO box (caixa postal) and CEP ranges.
+type CEPResultDetalhado struct {
+	CEPResult
+	// LocalidadeSubordinada is the district/village (distrito or povoado)
+	// the CEP belongs to, if any.
+	LocalidadeSubordinada string `json:"localidadeSubordinada"`
+	// LocNoSem is the city name without accents (e.g. "Sao Paulo").
+	LocNoSem string `json:"locNoSem"`
+	// IBGE is the IBGE municipality code. It is only filled by
+	// ConsultaCEPDetalhadoIBGE.
+	IBGE              string             `json:"ibge,omitempty"`
+	FaixasCaixaPostal []FaixaCaixaPostal `json:"faixasCaixaPostal"`
+	FaixasCep         []FaixaCEP         `json:"faixasCep"`
+}
+
+// FaixaCaixaPostal is a range of PO box (caixa postal) numbers.
+type FaixaCaixaPostal struct {
+	NumeroInicial string `json:"numeroInicial"`
+	NumeroFinal   string `json:"numeroFinal"`
+}
+
+// FaixaCEP is a range of CEPs.
+type FaixaCEP struct {
+	CEPInicial string `json:"cepInicial"`
+	CEPFinal   string `json:"cepFinal"`
+}
+
+// Detalhado converts the raw entry to a *CEPResultDetalhado.
+func (d RawCEPDado) Detalhado() *CEPResultDetalhado {
+	result := &CEPResultDetalhado{
+		CEPResult:             *d.CEPResult(),
+		LocalidadeSubordinada: d.LocalidadeSubordinada,
+		LocNoSem:              d.LocNoSem,
+		FaixasCaixaPostal:     make([]FaixaCaixaPostal, 0, len(d.FaixasCaixaPostal)),
+		FaixasCep:             make([]FaixaCEP, 0, len(d.FaixasCep)),
+	}
+	for _, v := range d.FaixasCaixaPostal {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		result.FaixasCaixaPostal = append(result.FaixasCaixaPostal, FaixaCaixaPostal{
+			NumeroInicial: faixaValor(m, "nuInicial", "numeroInicial", "inicial"),
+			NumeroFinal:   faixaValor(m, "nuFinal", "numeroFinal", "final"),
+		})
+	}
+	for _, v := range d.FaixasCep {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		result.FaixasCep = append(result.FaixasCep, FaixaCEP{
+			CEPInicial: faixaValor(m, "cepInicial", "nuCepInicial", "inicial"),
+			CEPFinal:   faixaValor(m, "cepFinal", "nuCepFinal", "final"),
+		})
+	}
+	return result
+}
+
+// faixaValor returns the first key of m that is present, as a string. The
+// Correios API is not consistent and may return these values as strings or
+// numbers.
+func faixaValor(m map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case string:
+			return v
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	return ""
 }
 
 // ConsultaCEP returns the street, city, UF and district (bairro) of a brazillian ZIP code.
 func ConsultaCEP(ctx context.Context, cep string) (*CEPResult, error) {
+	return DefaultClient.ConsultaCEP(ctx, cep)
+}
+
+// ConsultaCEPSimples works like ConsultaCEP using context.Background()
+// (handy for scripts and CLI tools). GlobalTimeout, if > 0, still applies.
+func ConsultaCEPSimples(cep string) (*CEPResult, error) {
+	return ConsultaCEP(context.Background(), cep)
+}
+
+// ConsultaCEPWith works like ConsultaCEP, but uses the provided
+// *http.Client (to set timeouts or share a transport). If client is nil,
+// http.DefaultClient is used.
+func ConsultaCEPWith(ctx context.Context, client *http.Client, cep string) (*CEPResult, error) {
+	return (&Client{HTTPClient: client}).ConsultaCEP(ctx, cep)
+}
+
+// ConsultaCEP returns the street, city, UF and district (bairro) of a
+// brazillian ZIP code using the Client configuration.
+func (c *Client) ConsultaCEP(ctx context.Context, cep string) (*CEPResult, error) {
+	cep = FilterCEP(cep)
+	if c.CEPCache != nil {
+		if r, ok := c.CEPCache.Get(cep); ok {
+			return r, nil
+		}
+	}
+	result, err := c.cepCompartilhado(ctx, cep, func(ctx context.Context) (*CEPResult, error) {
+		rawResp, err := c.buscaEndereco(ctx, cep)
+		if err == nil {
+			return rawResp.Dados[0].CEPResult(), nil
+		}
+		if c.ViaCEPFallback && ctx.Err() == nil {
+			return c.consultaViaCEP(ctx, cep)
+		}
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if c.CEPCache != nil {
+		c.CEPCache.Set(cep, result)
+	}
+	return result, nil
+}
+
+// ConsultaCEPBatch looks up many CEPs concurrently, using at most
+// concurrency simultaneous requests (1 if concurrency < 1). Results and
+// errors are keyed by the CEPs as given, so a failed lookup does not abort
+// the batch. If ctx is cancelled, the pending CEPs fail with ctx.Err().
+func ConsultaCEPBatch(ctx context.Context, ceps []string, concurrency int) (map[string]*CEPResult, map[string]error) {
+	return DefaultClient.ConsultaCEPBatch(ctx, ceps, concurrency)
+}
+
+// ConsultaCEPBatch works like the package level ConsultaCEPBatch, using the
+// Client configuration.
+func (c *Client) ConsultaCEPBatch(ctx context.Context, ceps []string, concurrency int) (map[string]*CEPResult, map[string]error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	results := make(map[string]*CEPResult, len(ceps))
+	errs := make(map[string]error)
+	l := sync.Mutex{}
+	jobs := make(chan string)
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for cep := range jobs {
+				var r *CEPResult
+				err := ctx.Err()
+				if err == nil {
+					r, err = c.ConsultaCEP(ctx, cep)
+				}
+				l.Lock()
+				if err != nil {
+					errs[cep] = err
+				} else {
+					results[cep] = r
+				}
+				l.Unlock()
+			}
+		}()
+	}
+	seen := make(map[string]bool, len(ceps))
+	for _, cep := range ceps {
+		if seen[cep] {
+			continue
+		}
+		seen[cep] = true
+		jobs <- cep
+	}
+	close(jobs)
+	wg.Wait()
+	return results, errs
+}
+
+// ConsultaCEPTodos returns all the entries (street, city, UF and district)
+// of a brazillian ZIP code. Some CEPs (city-wide or range CEPs) may return
+// more than one entry.
+func ConsultaCEPTodos(ctx context.Context, cep string) ([]CEPResult, error) {
+	return DefaultClient.ConsultaCEPTodos(ctx, cep)
+}
+
+// ConsultaCEPTodos returns all the entries of a brazillian ZIP code using
+// the Client configuration. See ConsultaCEPTodos.
+func (c *Client) ConsultaCEPTodos(ctx context.Context, cep string) ([]CEPResult, error) {
+	rawResp, err := c.ConsultaCEPRaw(ctx, cep)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]CEPResult, 0, len(rawResp.Dados))
+	for _, d := range rawResp.Dados {
+		results = append(results, *d.CEPResult())
+	}
+	return results, nil
+}
+
+// ConsultaCEPDetalhado returns the first entry of a brazillian ZIP code,
+// including the PO box (caixa postal) and CEP ranges.
+func ConsultaCEPDetalhado(ctx context.Context, cep string) (*CEPResultDetalhado, error) {
+	return DefaultClient.ConsultaCEPDetalhado(ctx, cep)
+}
+
+// ConsultaCEPDetalhado returns the first entry of a brazillian ZIP code
+// using the Client configuration. See ConsultaCEPDetalhado.
+func (c *Client) ConsultaCEPDetalhado(ctx context.Context, cep string) (*CEPResultDetalhado, error) {
+	rawResp, err := c.ConsultaCEPRaw(ctx, cep)
+	if err != nil {
+		return nil, err
+	}
+	return rawResp.Dados[0].Detalhado(), nil
+}
+
+// ConsultaCEPRaw returns the raw data of a brazillian ZIP code. If the
+// request succeeds, the result has at least one entry in Dados.
+func ConsultaCEPRaw(ctx context.Context, cep string) (*RawCEPResult, error) {
+	return DefaultClient.ConsultaCEPRaw(ctx, cep)
+}
+
+// ConsultaCEPRaw returns the raw data of a brazillian ZIP code using the
+// Client configuration. See ConsultaCEPRaw.
+func (c *Client) ConsultaCEPRaw(ctx context.Context, cep string) (*RawCEPResult, error) {
+	return c.buscaEndereco(ctx, FilterCEP(cep))
+}
+
+// BuscarCEPporEndereco searches the CEPs matching a street (logradouro) of a
+// city. The result may contain many candidates.
+func BuscarCEPporEndereco(ctx context.Context, uf, cidade, logradouro string) ([]CEPResult, error) {
+	return DefaultClient.BuscarCEPporEndereco(ctx, uf, cidade, logradouro)
+}
+
+// BuscarCEPporEndereco searches the CEPs matching a street of a city using
+// the Client configuration. See BuscarCEPporEndereco.
+func (c *Client) BuscarCEPporEndereco(ctx context.Context, uf, cidade, logradouro string) ([]CEPResult, error) {
+	parts := make([]string, 0, 3)
+	for _, v := range []string{logradouro, cidade, uf} {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	if len(parts) == 0 {
+		return nil, errors.New("correios: empty address")
+	}
+	rawResp, err := c.buscaEndereco(ctx, strings.Join(parts, " "))
+	if err != nil {
+		return nil, err
+	}
+	results := make([]CEPResult, 0, len(rawResp.Dados))
+	for _, d := range rawResp.Dados {
+		results = append(results, *d.CEPResult())
+	}
+	return results, nil
+}
+
+// BuscarPorPrefixoCEP returns the CEPs starting with a 5 digit prefix
+// (e.g. "13056"), useful for autocomplete. Broad prefixes may match many
+// rows, so at most limite results are returned (no limit if limite <= 0).
+func BuscarPorPrefixoCEP(ctx context.Context, prefixo string, limite int) ([]CEPResult, error) {
+	return DefaultClient.BuscarPorPrefixoCEP(ctx, prefixo, limite)
+}
+
+// BuscarPorPrefixoCEP returns the CEPs starting with a 5 digit prefix using
+// the Client configuration. See BuscarPorPrefixoCEP.
+func (c *Client) BuscarPorPrefixoCEP(ctx context.Context, prefixo string, limite int) ([]CEPResult, error) {
+	prefixo = FilterCEP(prefixo)
+	if len(prefixo) != 5 {
+		return nil, errors.New("correios: invalid CEP prefix")
+	}
+	rawResp, err := c.buscaEndereco(ctx, prefixo)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]CEPResult, 0, len(rawResp.Dados))
+	for _, d := range rawResp.Dados {
+		if !strings.HasPrefix(FilterCEP(d.Cep), prefixo) {
+			continue
+		}
+		results = append(results, *d.CEPResult())
+		if limite > 0 && len(results) >= limite {
+			break
+		}
+	}
+	if len(results) == 0 {
+		return nil, ErrNoResults
+	}
+	return results, nil
+}
+
+// buscaEndereco queries the buscacepinter endpoint; endereco can be a CEP
+// or an address.
+func (c *Client) buscaEndereco(ctx context.Context, endereco string) (*RawCEPResult, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
 	vals := url.Values{}
 	vals.Set("MIME Type", "application/x-www-form-urlencoded; charset=utf-8")
 	vals.Set("pagina", "/app/endereco/index.php")
 	vals.Set("cepaux", "")
 	vals.Set("mensagem_alerta", "")
-	vals.Set("endereco", FilterCEP(cep))
+	vals.Set("endereco", endereco)
 	vals.Set("tipoCEP", "ALL")
 	buf := bytes.NewBufferString(vals.Encode())
-	rq0, err := http.NewRequestWithContext(ctx, http.MethodPost, ConsultaCEPURL, buf)
+	rq0, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cepURL(), buf)
 	if err != nil {
 		return nil, err
 	}
 	rq0.Header.Set("Referer", ConsultaCEPReferer)
 	rq0.Header.Set("User-Agent", ConsultaCEPUserAgent)
 	rq0.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
-	cresp, err := http.DefaultClient.Do(rq0)
+	cresp, err := c.do(rq0)
 	if err != nil {
-		return nil, err
+		return nil, &TransporteError{Err: err}
 	}
 	defer cresp.Body.Close()
 	if cresp.StatusCode != http.StatusOK {
-		return nil, errors.New("http status: " + cresp.Status)
+		return nil, statusError(cresp)
 	}
 	rawResp := &RawCEPResult{}
 	if err := json.NewDecoder(cresp.Body).Decode(rawResp); err != nil {
-		return nil, fmt.Errorf("decode json error: %w", err)
+		return nil, &DecodeError{Err: fmt.Errorf("decode json error: %w", err)}
 	}
 	if rawResp.Erro {
 		return nil, errors.New("correios: " + rawResp.Mensagem)
@@ -107,16 +446,5 @@ func ConsultaCEP(ctx context.Context, cep string) (*CEPResult, error) {
 	if rawResp.Total == 0 || len(rawResp.Dados) == 0 {
 		return nil, ErrNoResults
 	}
-	result := &CEPResult{
-		CEP:    rawResp.Dados[0].Cep,
-		UF:     rawResp.Dados[0].Uf,
-		Cidade: rawResp.Dados[0].Localidade,
-		Bairro: rawResp.Dados[0].Bairro,
-	}
-	if rawResp.Dados[0].LogradouroDNEC != "" {
-		result.Logradouro = rawResp.Dados[0].LogradouroDNEC
-	} else if rawResp.Dados[0].LogradouroTexto != "" {
-		result.Logradouro = rawResp.Dados[0].LogradouroTexto
-	}
-	return result, nil
+	return rawResp, nil
 }
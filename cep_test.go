@@ -22,6 +22,9 @@ package correios_test
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/gabstv/correios"
@@ -29,6 +32,14 @@ import (
 )
 
 func TestConsultaCEP(t *testing.T) {
+	defer withCEPHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		if r.PostForm.Get("endereco") != "13056535" {
+			w.Write([]byte(`{"erro":true,"mensagem":"CEP NAO ENCONTRADO","total":0,"dados":[]}`))
+			return
+		}
+		w.Write([]byte(cepJSON))
+	})()
 	// not found
 	ctx, cf := context.WithCancel(context.Background())
 	defer cf()
@@ -41,3 +52,211 @@ func TestConsultaCEP(t *testing.T) {
 	assert.NotNil(t, r)
 	assert.Equal(t, "13056535", r.CEP)
 }
+
+func TestConsultaCEPSimples(t *testing.T) {
+	defer withCEPServer(t, cepJSON)()
+	r, err := correios.ConsultaCEPSimples("13056-535")
+	assert.NoError(t, err)
+	assert.Equal(t, "Campinas", r.Cidade)
+}
+
+func TestValidarCEP(t *testing.T) {
+	assert.True(t, correios.ValidarCEP("13056535"))
+	assert.True(t, correios.ValidarCEP("13056-535"))
+	assert.True(t, correios.ValidarCEP(" 13.056-535 "))
+	assert.False(t, correios.ValidarCEP("1305653"))
+	assert.False(t, correios.ValidarCEP("130565350"))
+	assert.False(t, correios.ValidarCEP("abc13056535"))
+	assert.False(t, correios.ValidarCEP(""))
+	assert.Equal(t, "13056535", correios.FilterCEP("13.056-535"))
+}
+
+const cepJSON = `{"erro":false,"mensagem":"DADOS ENCONTRADOS COM SUCESSO.","total":2,"dados":[` +
+	`{"uf":"SP","localidade":"Campinas","locNoSem":"","locNu":"","localidadeSubordinada":"","logradouroDNEC":"Rua Doutor Antônio Castro Prado Sobrinho","logradouroTextoAdicional":"","logradouroTexto":"","bairro":"Jardim Paulicéia","baiNu":"","nomeUnidade":"","cep":"13056535","tipoCep":"2","numeroLocalidade":"","situacao":"","faixasCaixaPostal":[],"faixasCep":[]},` +
+	`{"uf":"SP","localidade":"Campinas","locNoSem":"","locNu":"","localidadeSubordinada":"","logradouroDNEC":"","logradouroTextoAdicional":"","logradouroTexto":"Rua Sem Nome","bairro":"Jardim Paulicéia","baiNu":"","nomeUnidade":"","cep":"13056535","tipoCep":"2","numeroLocalidade":"","situacao":"","faixasCaixaPostal":[],"faixasCep":[]}` +
+	`]}`
+
+func withCEPServer(t *testing.T, body string) func() {
+	return withCEPHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	})
+}
+
+func withCEPHandler(t *testing.T, h http.HandlerFunc) func() {
+	srv := httptest.NewServer(h)
+	prev := correios.ConsultaCEPURL
+	correios.ConsultaCEPURL = srv.URL
+	return func() {
+		correios.ConsultaCEPURL = prev
+		srv.Close()
+	}
+}
+
+func TestConsultaCEPTodos(t *testing.T) {
+	defer withCEPServer(t, cepJSON)()
+	rs, err := correios.ConsultaCEPTodos(context.Background(), "13056-535")
+	assert.NoError(t, err)
+	assert.Len(t, rs, 2)
+	assert.Equal(t, "Rua Doutor Antônio Castro Prado Sobrinho", rs[0].Logradouro)
+	assert.Equal(t, "Rua Sem Nome", rs[1].Logradouro)
+	assert.Equal(t, "Campinas", rs[1].Cidade)
+	assert.Equal(t, "2", rs[0].TipoCep)
+
+	// Client configuration
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(cepJSON))
+	}))
+	defer srv.Close()
+	c := &correios.Client{CEPURL: srv.URL}
+	rs, err = c.ConsultaCEPTodos(context.Background(), "13056-535")
+	assert.NoError(t, err)
+	assert.Len(t, rs, 2)
+}
+
+func TestBuscarCEPporEndereco(t *testing.T) {
+	defer withCEPHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "Rua Sem Nome Campinas SP", r.PostForm.Get("endereco"))
+		w.Write([]byte(cepJSON))
+	})()
+	rs, err := correios.BuscarCEPporEndereco(context.Background(), "SP", "Campinas", "Rua Sem Nome")
+	assert.NoError(t, err)
+	assert.Len(t, rs, 2)
+	_, err = correios.BuscarCEPporEndereco(context.Background(), "", " ", "")
+	assert.Error(t, err)
+
+	// Client configuration; status errors carry the status code
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	c := &correios.Client{CEPURL: srv.URL}
+	_, err = c.BuscarCEPporEndereco(context.Background(), "SP", "Campinas", "Rua Sem Nome")
+	var te *correios.TransporteError
+	if assert.True(t, errors.As(err, &te)) {
+		assert.Equal(t, http.StatusServiceUnavailable, te.StatusCode())
+	}
+}
+
+func TestBuscarPorPrefixoCEP(t *testing.T) {
+	defer withCEPHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "13056", r.PostForm.Get("endereco"))
+		w.Write([]byte(`{"erro":false,"mensagem":"","total":3,"dados":[` +
+			`{"uf":"SP","localidade":"Campinas","logradouroDNEC":"Rua A","bairro":"Jardim Paulicéia","cep":"13056535"},` +
+			`{"uf":"SP","localidade":"Campinas","logradouroDNEC":"Rua B","bairro":"Jardim Paulicéia","cep":"13056540"},` +
+			`{"uf":"SP","localidade":"Campinas","logradouroDNEC":"Rua C","bairro":"Centro","cep":"13010000"}]}`))
+	})()
+	rs, err := correios.BuscarPorPrefixoCEP(context.Background(), "13056", 0)
+	assert.NoError(t, err)
+	// CEPs outside the prefix are discarded
+	assert.Len(t, rs, 2)
+	assert.Equal(t, "Rua B", rs[1].Logradouro)
+	rs, err = correios.BuscarPorPrefixoCEP(context.Background(), "13.056", 1)
+	assert.NoError(t, err)
+	assert.Len(t, rs, 1)
+	_, err = correios.BuscarPorPrefixoCEP(context.Background(), "1305", 0)
+	assert.Error(t, err)
+}
+
+func TestConsultaCEPDetalhado(t *testing.T) {
+	defer withCEPServer(t, `{"erro":false,"mensagem":"","total":1,"dados":[`+
+		`{"uf":"SP","localidade":"Campinas","locNoSem":"Campinas","localidadeSubordinada":"Sousas","logradouroDNEC":"","bairro":"Centro","nomeUnidade":"AC Campinas","cep":"13010971","tipoCep":"4",`+
+		`"faixasCaixaPostal":[{"nuInicial":1,"nuFinal":"3000"}],"faixasCep":[{"cepInicial":"13010970","cepFinal":"13010979"}]}]}`)()
+	r, err := correios.ConsultaCEPDetalhado(context.Background(), "13010-971")
+	assert.NoError(t, err)
+	assert.Equal(t, "AC Campinas", r.NomeUnidade)
+	assert.Equal(t, "Campinas", r.LocNoSem)
+	assert.Equal(t, "Sousas", r.LocalidadeSubordinada)
+	assert.Equal(t, []correios.FaixaCaixaPostal{{NumeroInicial: "1", NumeroFinal: "3000"}}, r.FaixasCaixaPostal)
+	assert.Equal(t, []correios.FaixaCEP{{CEPInicial: "13010970", CEPFinal: "13010979"}}, r.FaixasCep)
+	assert.True(t, r.EhCaixaPostal())
+
+	// Client configuration
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(cepJSON))
+	}))
+	defer srv.Close()
+	c := &correios.Client{CEPURL: srv.URL}
+	r, err = c.ConsultaCEPDetalhado(context.Background(), "13056-535")
+	assert.NoError(t, err)
+	assert.Equal(t, "Campinas", r.Cidade)
+	raw, err := c.ConsultaCEPRaw(context.Background(), "13056-535")
+	assert.NoError(t, err)
+	assert.Len(t, raw.Dados, 2)
+}
+
+func TestEhCaixaPostal(t *testing.T) {
+	assert.False(t, (&correios.CEPResult{TipoCep: "2"}).EhCaixaPostal())
+	assert.True(t, (&correios.CEPResult{TipoCep: "5"}).EhCaixaPostal())
+	assert.True(t, (&correios.CEPResult{TipoCep: "2", CaixaPostal: true}).EhCaixaPostal())
+	d := correios.RawCEPDado{TipoCep: "2", FaixasCaixaPostal: []interface{}{map[string]interface{}{"nuInicial": "1", "nuFinal": "100"}}}
+	assert.True(t, d.CEPResult().EhCaixaPostal())
+	d.FaixasCaixaPostal = nil
+	assert.False(t, d.CEPResult().EhCaixaPostal())
+}
+
+func TestConsultaCEPBatch(t *testing.T) {
+	defer withCEPHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		if r.PostForm.Get("endereco") != "13056535" {
+			w.Write([]byte(`{"erro":true,"mensagem":"CEP NAO ENCONTRADO","total":0,"dados":[]}`))
+			return
+		}
+		w.Write([]byte(cepJSON))
+	})()
+	rs, errs := correios.ConsultaCEPBatch(context.Background(), []string{"13056-535", "13056535", "00000000", "13056-535"}, 2)
+	assert.Len(t, rs, 2)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "Campinas", rs["13056-535"].Cidade)
+	assert.Equal(t, "Campinas", rs["13056535"].Cidade)
+	assert.Error(t, errs["00000000"])
+
+	ctx, cf := context.WithCancel(context.Background())
+	cf()
+	rs, errs = correios.ConsultaCEPBatch(ctx, []string{"13056535", "01243000"}, 0)
+	assert.Len(t, rs, 0)
+	assert.Equal(t, context.Canceled, errs["01243000"])
+}
+
+func TestConsultaCEPViaCEPFallback(t *testing.T) {
+	defer withCEPHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})()
+	via := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ws/99999999/json/" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if r.URL.Path != "/ws/13056535/json/" {
+			w.Write([]byte(`{"erro": "true"}`))
+			return
+		}
+		w.Write([]byte(`{"cep":"13056-535","logradouro":"Rua Doutor Antônio Castro Prado Sobrinho",` +
+			`"bairro":"Jardim Paulicéia","localidade":"Campinas","uf":"SP","ibge":"3509502"}`))
+	}))
+	defer via.Close()
+	prev := correios.ViaCEPURL
+	correios.ViaCEPURL = via.URL + "/ws/"
+	defer func() { correios.ViaCEPURL = prev }()
+
+	c := &correios.Client{}
+	_, err := c.ConsultaCEP(context.Background(), "13056-535")
+	assert.Error(t, err)
+
+	c.ViaCEPFallback = true
+	r, err := c.ConsultaCEP(context.Background(), "13056-535")
+	assert.NoError(t, err)
+	assert.Equal(t, "13056535", r.CEP)
+	assert.Equal(t, "Campinas", r.Cidade)
+	assert.Equal(t, "SP", r.UF)
+	_, err = c.ConsultaCEP(context.Background(), "01243000")
+	assert.Equal(t, correios.ErrNoResults, err)
+
+	_, err = c.ConsultaCEP(context.Background(), "99999999")
+	var te *correios.TransporteError
+	if assert.True(t, errors.As(err, &te)) {
+		assert.Equal(t, http.StatusTooManyRequests, te.StatusCode())
+	}
+}
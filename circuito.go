@@ -0,0 +1,167 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitoAberto é retornado por CalcularFrete quando o CircuitBreaker
+// do Client está aberto e não há FallbackFunc
+var ErrCircuitoAberto = errors.New("correios: circuito aberto (falhas consecutivas); nova tentativa em breve")
+
+// EstadoCircuito é o estado de um CircuitBreaker
+type EstadoCircuito int
+
+const (
+	// CircuitoFechado os requests são enviados normalmente
+	CircuitoFechado EstadoCircuito = iota
+	// CircuitoAberto os requests não são enviados (fallback ou
+	// ErrCircuitoAberto)
+	CircuitoAberto
+	// CircuitoSemiAberto um único request de teste é enviado; em caso de
+	// sucesso o circuito é fechado, senão volta a ser aberto
+	CircuitoSemiAberto
+)
+
+// String retorna o nome do estado
+func (e EstadoCircuito) String() string {
+	switch e {
+	case CircuitoFechado:
+		return "fechado"
+	case CircuitoAberto:
+		return "aberto"
+	case CircuitoSemiAberto:
+		return "semi-aberto"
+	}
+	return "desconhecido"
+}
+
+// CircuitBreaker interrompe as consultas de frete ao FreteEndpoint após
+// falhas consecutivas (erros de rede, status HTTP != 200 ou respostas que
+// não podem ser decodificadas), evitando sobrecarregar os Correios durante
+// uma indisponibilidade. Enquanto aberto, FallbackFunc é utilizada (se
+// definida) ou ErrCircuitoAberto é retornado. Após Intervalo, um request de
+// teste é enviado (semi-aberto). Deve ser utilizado como ponteiro e pode ser
+// compartilhado entre Clients.
+type CircuitBreaker struct {
+	// Falhas é o número de falhas consecutivas que abre o circuito; se <= 0, 5
+	Falhas int
+	// Intervalo é o tempo em que o circuito permanece aberto antes do
+	// request de teste; se <= 0, 30s
+	Intervalo time.Duration
+
+	l        sync.Mutex
+	estado   EstadoCircuito
+	falhas   int
+	abertoEm time.Time
+	testando bool
+}
+
+func (cb *CircuitBreaker) limite() int {
+	if cb.Falhas <= 0 {
+		return 5
+	}
+	return cb.Falhas
+}
+
+func (cb *CircuitBreaker) intervalo() time.Duration {
+	if cb.Intervalo <= 0 {
+		return 30 * time.Second
+	}
+	return cb.Intervalo
+}
+
+// Estado retorna o estado atual do circuito
+func (cb *CircuitBreaker) Estado() EstadoCircuito {
+	cb.l.Lock()
+	defer cb.l.Unlock()
+	if cb.estado == CircuitoAberto && time.Since(cb.abertoEm) >= cb.intervalo() {
+		return CircuitoSemiAberto
+	}
+	return cb.estado
+}
+
+// permitir informa se um request pode ser enviado. No estado semi-aberto,
+// somente um request de teste é permitido por vez.
+func (cb *CircuitBreaker) permitir() bool {
+	cb.l.Lock()
+	defer cb.l.Unlock()
+	switch cb.estado {
+	case CircuitoAberto:
+		if time.Since(cb.abertoEm) < cb.intervalo() {
+			return false
+		}
+		cb.estado = CircuitoSemiAberto
+		cb.testando = true
+		return true
+	case CircuitoSemiAberto:
+		if cb.testando {
+			return false
+		}
+		cb.testando = true
+		return true
+	}
+	return true
+}
+
+// resultadoCircuito é o resultado de um request, registrado no circuito
+type resultadoCircuito int
+
+const (
+	circuitoSucesso resultadoCircuito = iota
+	circuitoFalha
+	// circuitoNeutro é um request cancelado pelo chamador, que não indica
+	// nem a disponibilidade nem a indisponibilidade dos Correios
+	circuitoNeutro
+)
+
+// resultadoRequest classifica o resultado de um request feito com ctx
+func resultadoRequest(ctx context.Context, err error) resultadoCircuito {
+	switch {
+	case err == nil:
+		return circuitoSucesso
+	case ctx.Err() != nil:
+		return circuitoNeutro
+	}
+	return circuitoFalha
+}
+
+// registrar atualiza o circuito com o resultado de um request permitido
+func (cb *CircuitBreaker) registrar(r resultadoCircuito) {
+	cb.l.Lock()
+	defer cb.l.Unlock()
+	cb.testando = false
+	switch r {
+	case circuitoSucesso:
+		cb.estado = CircuitoFechado
+		cb.falhas = 0
+	case circuitoFalha:
+		cb.falhas++
+		if cb.estado == CircuitoSemiAberto || cb.falhas >= cb.limite() {
+			cb.estado = CircuitoAberto
+			cb.abertoEm = time.Now()
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	n, falhar := 0, true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if falhar {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	cb := &correios.CircuitBreaker{Falhas: 2, Intervalo: 50 * time.Millisecond}
+	c := &correios.Client{FreteEndpoint: srv.URL, CircuitBreaker: cb}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := c.CalcularFrete(ctx, r)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 2, n)
+	assert.Equal(t, correios.CircuitoAberto, cb.Estado())
+
+	// aberto: nenhum request é enviado
+	_, err := c.CalcularFrete(ctx, r)
+	assert.Equal(t, correios.ErrCircuitoAberto, err)
+	c.FallbackFunc = func(ctx context.Context, v url.Values) (*correios.FreteResponse, error) {
+		return testFreteResponse(), nil
+	}
+	resp, err := c.CalcularFrete(ctx, r)
+	assert.NoError(t, err)
+	assert.Equal(t, correios.OrigemFallback, resp.Origem)
+	assert.Equal(t, 2, n)
+	c.FallbackFunc = nil
+
+	// semi-aberto: o request de teste falha e o circuito volta a abrir
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, correios.CircuitoSemiAberto, cb.Estado())
+	_, err = c.CalcularFrete(ctx, r)
+	assert.Error(t, err)
+	assert.NotEqual(t, correios.ErrCircuitoAberto, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, correios.CircuitoAberto, cb.Estado())
+
+	// semi-aberto: o request de teste funciona e o circuito é fechado
+	time.Sleep(60 * time.Millisecond)
+	falhar = false
+	resp, err = c.CalcularFrete(ctx, r)
+	assert.NoError(t, err)
+	assert.Equal(t, correios.OrigemCorreios, resp.Origem)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, correios.CircuitoFechado, cb.Estado())
+	assert.Equal(t, "fechado", cb.Estado().String())
+}
+
+func TestCircuitBreakerCancelamento(t *testing.T) {
+	var lento int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&lento) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	cb := &correios.CircuitBreaker{Falhas: 2, Intervalo: 50 * time.Millisecond}
+	c := &correios.Client{FreteEndpoint: srv.URL, CircuitBreaker: cb}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+
+	_, err := c.CalcularFrete(context.Background(), r)
+	assert.Error(t, err)
+	assert.Equal(t, correios.CircuitoFechado, cb.Estado())
+
+	// o cancelamento não zera as falhas consecutivas
+	atomic.StoreInt32(&lento, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	_, err = c.CalcularFrete(ctx, r)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	atomic.StoreInt32(&lento, 0)
+
+	_, err = c.CalcularFrete(context.Background(), r)
+	assert.Error(t, err)
+	assert.Equal(t, correios.CircuitoAberto, cb.Estado())
+
+	// um request de teste cancelado não fecha o circuito
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&lento, 1)
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel2()
+	_, err = c.CalcularFrete(ctx2, r)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, correios.CircuitoSemiAberto, cb.Estado())
+}
+
+func TestCircuitBreakerCompartilhado(t *testing.T) {
+	var n int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	cb := &correios.CircuitBreaker{Falhas: 2, Intervalo: time.Minute}
+	c := &correios.Client{FreteEndpoint: srv.URL, CircuitBreaker: cb}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+			_, err := c.CalcularFrete(context.Background(), r)
+			assert.Error(t, err)
+		}()
+	}
+	wg.Wait()
+	// uma única falha compartilhada por 3 chamadores
+	assert.Equal(t, int32(1), atomic.LoadInt32(&n))
+	assert.Equal(t, correios.CircuitoFechado, cb.Estado())
+}
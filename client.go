@@ -0,0 +1,281 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+)
+
+// Client armazena as configurações utilizadas nos requests aos Correios,
+// permitindo utilizar configurações isoladas (ex.: vários contratos) no
+// mesmo processo. Os campos não preenchidos utilizam as variáveis do pacote
+// (FreteEndpoint, FreteSOAPEndpoint, ConsultaCEPURL, PrazoEndpoint,
+// RastreioEndpoint, ViaCEPURL, IBGEURL, GlobalTimeout, Retry, FallbackFunc).
+type Client struct {
+	// HTTPClient é o *http.Client utilizado; se nil, http.DefaultClient.
+	// Reutilize o mesmo Client entre requests p/ aproveitar as conexões
+	// abertas (keep-alive); ver Close.
+	HTTPClient *http.Client
+	// FreteEndpoint é o endpoint utilizado para calcular o frete
+	FreteEndpoint string
+	// CEPURL é o endpoint utilizado para consultar CEPs
+	CEPURL string
+	// PrazoEndpoint é o endpoint utilizado por CalcularPrazo
+	PrazoEndpoint string
+	// RastreioEndpoint é o endpoint utilizado por Rastrear
+	RastreioEndpoint string
+	// ViaCEPURL é o endpoint do ViaCEP (ver ViaCEPFallback)
+	ViaCEPURL string
+	// CdEmpresa e DsSenha são utilizados nos requests de frete que não
+	// informarem o código da empresa (ver WithCredentials)
+	CdEmpresa string
+	DsSenha   string
+	// Timeout é aplicado aos requests cujo context não possua um deadline
+	Timeout time.Duration
+	// Retry é a política de novas tentativas
+	Retry *RetryConfig
+	// FallbackFunc é chamada quando o request de frete falha
+	FallbackFunc func(ctx context.Context, v url.Values) (*FreteResponse, error)
+	// AlwaysUseFallback faz com que FallbackFunc seja sempre utilizada
+	AlwaysUseFallback bool
+	// RawResponseFunc, se definida, recebe o corpo de cada resposta do
+	// FreteEndpoint (antes da decodificação) e os parâmetros enviados. Útil
+	// p/ depuração. Pode ser chamada concorrentemente (RequestModeSingle).
+	RawResponseFunc func(v url.Values, body []byte)
+	// CEPCache, se definido, é consultado por ConsultaCEP antes do request
+	CEPCache CEPCache
+	// Logger, se definido, é notificado a cada request HTTP
+	Logger Logger
+	// CWS, se definido, faz com que CalcularFrete utilize a API REST dos
+	// Correios (ver CWSConfig)
+	CWS *CWSConfig
+	// UserAgent, se vazio, FreteUserAgent
+	UserAgent string
+	// Header são headers adicionais enviados nas consultas de frete e prazo
+	Header http.Header
+	// ViaCEPFallback faz com que ConsultaCEP utilize o ViaCEP (ViaCEPURL)
+	// quando a consulta aos Correios falha
+	ViaCEPFallback bool
+	// RateLimiter, se definido, limita a taxa de requests HTTP do Client
+	// (frete, CEP, rastreio). Os requests aguardam o limiter, respeitando o
+	// context. Ex.: rate.NewLimiter(5, 1) para 5 requests por segundo.
+	RateLimiter *rate.Limiter
+	// FreteSOAP faz com que as consultas de frete sejam enviadas ao serviço
+	// SOAP CalcPrecoPrazo (FreteSOAPEndpoint) em vez do FreteEndpoint
+	FreteSOAP bool
+	// FreteSOAPEndpoint, se vazio, FreteSOAPEndpoint do pacote
+	FreteSOAPEndpoint string
+	// StreamConcurrency é o número de requests simultâneos de
+	// CalcularFreteStream; se <= 0, 4
+	StreamConcurrency int
+
+	// ClampValorDeclarado faz com que CalcularFrete ajuste o valor declarado
+	// ao intervalo aceito pelos Correios antes do envio (ver
+	// FreteRequest.ClampValorDeclarado). O ajuste é informado em
+	// FreteResponse.AjusteValorDeclarado.
+	ClampValorDeclarado bool
+	// FreteCache, se definido, é consultado por CalcularFrete antes do
+	// request (ex.: NewMemFreteCache). As respostas obtidas do cache possuem
+	// Origem == OrigemCache; ver também FreteRequest.IgnorarCache.
+	FreteCache FreteCache
+	// Metricas, se definida, é notificada com o resultado de cada consulta
+	// de frete (ex.: NewMetricasExpvar)
+	Metricas Metricas
+	// PermitirServicosDesconhecidos faz com que ValidarFrete (e
+	// FreteRequestFromValues) aceite códigos de serviço que não constam em
+	// ServicosConhecidos (ex.: códigos de contrato ainda sem constante no
+	// pacote)
+	PermitirServicosDesconhecidos bool
+	// CircuitBreaker, se definido, interrompe as consultas de frete após
+	// falhas consecutivas (ver CircuitBreaker)
+	CircuitBreaker *CircuitBreaker
+	// IBGEURL é o endpoint utilizado por CodigoIBGE
+	IBGEURL string
+
+	// sf compartilha requests simultâneos idênticos (ver dedup.go)
+	sf singleflight.Group
+	// voos são os contexts das chamadas compartilhadas em andamento
+	voosL sync.Mutex
+	voos  map[string]*voo
+	// ibge armazena os municípios já consultados (ver ibge.go)
+	ibge ibgeCache
+}
+
+// DefaultClient é o Client utilizado pelas funções do pacote
+var DefaultClient = &Client{}
+
+// CorreiosClient contém as consultas principais do Client, permitindo
+// depender de uma interface e utilizar implementações falsas em testes
+// (ver também o pacote correiostest)
+type CorreiosClient interface {
+	CalcularFrete(ctx context.Context, req *FreteRequest) (*FreteResponse, error)
+	ConsultaCEP(ctx context.Context, cep string) (*CEPResult, error)
+}
+
+var _ CorreiosClient = (*Client)(nil)
+
+// WithCredentials define o código da empresa e a senha do contrato
+// utilizados nos requests de frete que não informarem o código da empresa.
+// Retorna o próprio Client.
+func (c *Client) WithCredentials(cdEmpresa, dsSenha string) *Client {
+	c.CdEmpresa = cdEmpresa
+	c.DsSenha = dsSenha
+	return c
+}
+
+// Validate verifica se CdEmpresa e DsSenha foram informados juntos. O erro
+// retornado é um *ValidacaoError (ErrCodigoOuSenha).
+func (c *Client) Validate() error {
+	return validarCredenciais(c.CdEmpresa, c.DsSenha)
+}
+
+// NewClient retorna um Client com os endpoints atuais do pacote já
+// preenchidos, de modo que alterações posteriores nas variáveis do pacote
+// (ex.: em testes) não afetem o Client.
+func NewClient() *Client {
+	return &Client{
+		FreteEndpoint:     FreteEndpoint,
+		FreteSOAPEndpoint: FreteSOAPEndpoint,
+		CEPURL:            ConsultaCEPURL,
+		PrazoEndpoint:     PrazoEndpoint,
+		RastreioEndpoint:  RastreioEndpoint,
+		ViaCEPURL:         ViaCEPURL,
+		IBGEURL:           IBGEURL,
+	}
+}
+
+// Close fecha as conexões ociosas do HTTPClient (ver
+// http.Client.CloseIdleConnections), útil ao encerrar workers de curta
+// duração. O Client pode continuar sendo utilizado após Close; novas
+// conexões são abertas conforme necessário. Se HTTPClient for nil, nada é
+// feito, pois http.DefaultClient é compartilhado. Sempre retorna nil.
+func (c *Client) Close() error {
+	if c.HTTPClient != nil {
+		c.HTTPClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (c *Client) freteEndpoint() string {
+	if c.FreteEndpoint != "" {
+		return c.FreteEndpoint
+	}
+	return FreteEndpoint
+}
+
+func (c *Client) freteSOAPEndpoint() string {
+	if c.FreteSOAPEndpoint != "" {
+		return c.FreteSOAPEndpoint
+	}
+	return FreteSOAPEndpoint
+}
+
+func (c *Client) cepURL() string {
+	if c.CEPURL != "" {
+		return c.CEPURL
+	}
+	return ConsultaCEPURL
+}
+
+func (c *Client) prazoEndpoint() string {
+	if c.PrazoEndpoint != "" {
+		return c.PrazoEndpoint
+	}
+	return PrazoEndpoint
+}
+
+func (c *Client) rastreioEndpoint() string {
+	if c.RastreioEndpoint != "" {
+		return c.RastreioEndpoint
+	}
+	return RastreioEndpoint
+}
+
+func (c *Client) viaCEPURL() string {
+	if c.ViaCEPURL != "" {
+		return c.ViaCEPURL
+	}
+	return ViaCEPURL
+}
+
+func (c *Client) ibgeURL() string {
+	if c.IBGEURL != "" {
+		return c.IBGEURL
+	}
+	return IBGEURL
+}
+
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Timeout > 0 {
+		return withTimeout(ctx, c.Timeout)
+	}
+	return withTimeout(ctx, GlobalTimeout)
+}
+
+func (c *Client) retry() *RetryConfig {
+	if c.Retry != nil {
+		return c.Retry
+	}
+	return Retry
+}
+
+func (c *Client) fallbackFunc() func(ctx context.Context, v url.Values) (*FreteResponse, error) {
+	if c.FallbackFunc != nil {
+		return c.FallbackFunc
+	}
+	return FallbackFunc
+}
+
+func (c *Client) alwaysUseFallback() bool {
+	return c.AlwaysUseFallback || AlwaysUseFallback
+}
+
+func (c *Client) userAgent() string {
+	if c.UserAgent != "" {
+		return c.UserAgent
+	}
+	return FreteUserAgent
+}
+
+// setFreteHeaders aplica o User-Agent e os headers adicionais do Client
+func (c *Client) setFreteHeaders(rq *http.Request) {
+	rq.Header.Set("User-Agent", c.userAgent())
+	for k, vs := range c.Header {
+		rq.Header.Del(k)
+		for _, v := range vs {
+			rq.Header.Add(k, v)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestClient(t *testing.T) {
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		q := r.URL.Query()
+		assert.Equal(t, "empresa", q.Get("nCdEmpresa"))
+		assert.Equal(t, "senha", q.Get("sDsSenha"))
+		assert.Equal(t, "04014,04510", q.Get("nCdServico"))
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	c := &correios.Client{
+		FreteEndpoint: srv.URL,
+		CdEmpresa:     "empresa",
+		DsSenha:       "senha",
+	}
+	r := correios.NewFreteRequest("01243000", "65299970")
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "42.5", resp.Any().Preco.String())
+	// o request original não é alterado
+	assert.Equal(t, "", r.CdEmpresa)
+}
+
+func TestClientRawResponseFunc(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>indisponível</html>"))
+	}))
+	defer srv.Close()
+	var raw []byte
+	c := &correios.Client{
+		FreteEndpoint: srv.URL,
+		RawResponseFunc: func(v url.Values, body []byte) {
+			assert.Equal(t, "04014", v.Get("nCdServico"))
+			raw = append([]byte(nil), body...)
+		},
+	}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	_, err := c.CalcularFrete(context.Background(), r)
+	assert.Error(t, err)
+	assert.Equal(t, "<html>indisponível</html>", string(raw))
+}
+
+func TestClientLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	var infos []correios.RequestInfo
+	c := &correios.Client{
+		FreteEndpoint: srv.URL,
+		CdEmpresa:     "empresa",
+		DsSenha:       "segredo",
+		Logger: correios.LoggerFunc(func(info correios.RequestInfo) {
+			infos = append(infos, info)
+		}),
+	}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	_, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Len(t, infos, 1)
+	assert.Equal(t, http.MethodGet, infos[0].Method)
+	assert.Equal(t, http.StatusOK, infos[0].StatusCode)
+	assert.NotContains(t, infos[0].URL, "segredo")
+	assert.Contains(t, infos[0].URL, srv.URL)
+}
+
+func TestClientHeaders(t *testing.T) {
+	var hdr http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hdr = r.Header
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	c := &correios.Client{FreteEndpoint: srv.URL}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	_, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, correios.FreteUserAgent, hdr.Get("User-Agent"))
+
+	c.UserAgent = "minha-loja/1.0"
+	c.Header = http.Header{"X-Api-Key": []string{"abc"}}
+	_, err = c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, "minha-loja/1.0", hdr.Get("User-Agent"))
+	assert.Equal(t, "abc", hdr.Get("X-Api-Key"))
+}
+
+func TestClientRateLimiter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	c := &correios.Client{
+		FreteEndpoint: srv.URL,
+		RateLimiter:   rate.NewLimiter(rate.Every(50*time.Millisecond), 1),
+	}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := c.CalcularFrete(context.Background(), r)
+		assert.NoError(t, err)
+	}
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+
+	// o limiter saturado respeita o context
+	ctx, cf := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cf()
+	c.RateLimiter = rate.NewLimiter(rate.Every(time.Hour), 1)
+	c.RateLimiter.Allow()
+	_, err := c.CalcularFrete(ctx, r)
+	assert.Error(t, err)
+}
+
+func TestNewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	c := correios.NewClient()
+	assert.Equal(t, correios.FreteEndpoint, c.FreteEndpoint)
+	assert.Equal(t, correios.ConsultaCEPURL, c.CEPURL)
+	assert.Equal(t, correios.PrazoEndpoint, c.PrazoEndpoint)
+	assert.Equal(t, correios.RastreioEndpoint, c.RastreioEndpoint)
+	assert.Equal(t, correios.ViaCEPURL, c.ViaCEPURL)
+
+	// alterar o Client não altera as variáveis do pacote
+	prev := correios.FreteEndpoint
+	c.FreteEndpoint = srv.URL
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, "42.5", resp.Any().Preco.String())
+	assert.Equal(t, prev, correios.FreteEndpoint)
+}
+
+func TestClientWithCredentials(t *testing.T) {
+	var q url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q = r.URL.Query()
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	c := (&correios.Client{FreteEndpoint: srv.URL}).WithCredentials("empresa", "senha")
+	assert.NoError(t, c.Validate())
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	_, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, "empresa", q.Get("nCdEmpresa"))
+
+	// o request pode informar outro contrato
+	r.CdEmpresa = "outra"
+	r.DsSenha = "outra-senha"
+	_, err = c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, "outra", q.Get("nCdEmpresa"))
+
+	c.WithCredentials("empresa", "")
+	var ve *correios.ValidacaoError
+	if assert.True(t, errors.As(c.Validate(), &ve)) {
+		assert.Equal(t, correios.ErrCodigoOuSenha, ve.Codigo)
+		assert.Equal(t, "DsSenha", ve.Campo)
+	}
+	_, err = c.CalcularFrete(context.Background(), r)
+	assert.True(t, errors.As(err, &ve))
+
+	r = correios.NewFreteRequest("01243000", "65299970")
+	r.DsSenha = "senha"
+	assert.Equal(t, correios.ErrCodigoOuSenha, codigo(r.Validate()))
+}
+
+func TestClientClampValorDeclarado(t *testing.T) {
+	var vd string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vd = r.URL.Query().Get("nVlValorDeclarado")
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	c := &correios.Client{FreteEndpoint: srv.URL}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo).SetValorDeclaradoFloat(15000)
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, "15000", vd)
+	assert.Nil(t, resp.AjusteValorDeclarado)
+
+	c.ClampValorDeclarado = true
+	resp, err = c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, "10000", vd)
+	if assert.NotNil(t, resp.AjusteValorDeclarado) {
+		assert.Equal(t, "15000", resp.AjusteValorDeclarado.Original.String())
+	}
+	// o request original não é alterado
+	assert.Equal(t, "15000", r.ValorDeclarado.String())
+}
+
+type fakeCorreios struct{}
+
+func (fakeCorreios) CalcularFrete(ctx context.Context, req *correios.FreteRequest) (*correios.FreteResponse, error) {
+	return &correios.FreteResponse{Servicos: map[correios.TipoServico]correios.ServicoResponse{
+		correios.SvcPACVarejo: {Tipo: correios.SvcPACVarejo},
+	}}, nil
+}
+
+func (fakeCorreios) ConsultaCEP(ctx context.Context, cep string) (*correios.CEPResult, error) {
+	return &correios.CEPResult{CEP: cep}, nil
+}
+
+func TestCorreiosClient(t *testing.T) {
+	clients := []correios.CorreiosClient{correios.DefaultClient, fakeCorreios{}}
+	resp, err := clients[1].CalcularFrete(context.Background(), correios.NewFreteRequest("01243000", "65299970"))
+	assert.NoError(t, err)
+	assert.Equal(t, correios.SvcPACVarejo, resp.Any().Tipo)
+	assert.NotNil(t, clients[0])
+}
+
+type closeIdleTransport struct {
+	http.RoundTripper
+	fechadas int
+}
+
+func (t *closeIdleTransport) CloseIdleConnections() {
+	t.fechadas++
+}
+
+func TestClientClose(t *testing.T) {
+	tr := &closeIdleTransport{RoundTripper: http.DefaultTransport}
+	c := &correios.Client{HTTPClient: &http.Client{Transport: tr}}
+	assert.NoError(t, c.Close())
+	assert.Equal(t, 1, tr.fechadas)
+	assert.NoError(t, (&correios.Client{}).Close())
+}
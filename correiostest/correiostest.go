@@ -0,0 +1,268 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package correiostest provides a fake Correios server for tests.
+package correiostest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gabstv/correios"
+)
+
+// Servico is the canned response of a service code.
+type Servico struct {
+	Codigo                correios.TipoServico
+	Valor                 string // in the Correios format, e.g. "42,50"
+	PrazoEntrega          int
+	ValorSemAdicionais    string
+	ValorMaoPropria       string
+	ValorAvisoRecebimento string
+	ValorValorDeclarado   string
+	EntregaDomiciliar     bool
+	EntregaSabado         bool
+	Erro                  correios.TipoErro
+	MsgErro               string
+	DataMaxEntrega        string // e.g. "15/06/2021"; omitted if empty
+	ObsFim                string
+}
+
+// SEDEX is the default response of correios.SvcSEDEXVarejo.
+var SEDEX = Servico{
+	Codigo:                correios.SvcSEDEXVarejo,
+	Valor:                 "42,50",
+	PrazoEntrega:          2,
+	ValorSemAdicionais:    "42,50",
+	ValorMaoPropria:       "0,00",
+	ValorAvisoRecebimento: "0,00",
+	ValorValorDeclarado:   "0,00",
+	EntregaDomiciliar:     true,
+	EntregaSabado:         true,
+}
+
+// PAC is the default response of correios.SvcPACVarejo.
+var PAC = Servico{
+	Codigo:                correios.SvcPACVarejo,
+	Valor:                 "21,90",
+	PrazoEntrega:          7,
+	ValorSemAdicionais:    "21,90",
+	ValorMaoPropria:       "0,00",
+	ValorAvisoRecebimento: "0,00",
+	ValorValorDeclarado:   "0,00",
+	EntregaDomiciliar:     true,
+	EntregaSabado:         false,
+}
+
+// CodigosErro are all the error codes defined by the correios package.
+var CodigosErro = []correios.TipoErro{
+	correios.ErrTipoServicoInvalido, correios.ErrCepOrigemInvalido, correios.ErrCepDestinoInvalido,
+	correios.ErrCepPesoExcedido, correios.ErrValorDeclaradoAlto10k, correios.ErrServicoIndisponivelTrecho,
+	correios.ErrValorDeclaradoObrigatorio, correios.ErrMaoPropriaIndisponivel, correios.ErrAvisoRecebimentoIndisponivel,
+	correios.ErrPrecificacaoIndisponivel, correios.ErrInformarDimensoes, correios.ErrComprimento,
+	correios.ErrLargura, correios.ErrAltura, correios.ErrComprimento105, correios.ErrLargura105,
+	correios.ErrAltura105, correios.ErrAlturaInferior, correios.ErrLarguraInferior, correios.ErrComprimentoInferior,
+	correios.ErrDimensoesSoma, correios.ErrComprimento2, correios.ErrDiametro, correios.ErrComprimento3,
+	correios.ErrDiametro2, correios.ErrComprimento4, correios.ErrDiametro91, correios.ErrComprimento18,
+	correios.ErrDiametro5, correios.ErrSomaDiametro, correios.ErrSistemaIndisponivel, correios.ErrCodigoOuSenha,
+	correios.ErrSenha, correios.ErrSemContrato, correios.ErrSemServicoAtivo, correios.ErrServicoIndisponivelAdmin,
+	correios.ErrPesoExcedidoEnvelope, correios.ErrInformarDimensoes2, correios.ErrComprimento60,
+	correios.ErrComprimento16, correios.ErrComprimentoLargura120, correios.ErrLarguraInferior2,
+	correios.ErrLarguraSuperior60, correios.ErrErroCalculoTarifa, correios.ErrLocalidadeOrigem,
+	correios.ErrLocalidadeDestino, correios.ErrServicoIndisponivelTrecho2, correios.ErrAreaDeRiscoCEPInicial,
+	correios.ErrAreaPrazoDiferenciado, correios.ErrAreaDeRiscoCEPs, correios.ErrIndeterminado,
+}
+
+// Erro returns the canned response of a service that failed with codigo.
+func Erro(svc correios.TipoServico, codigo correios.TipoErro) Servico {
+	return Servico{
+		Codigo:                svc,
+		Valor:                 "0,00",
+		ValorSemAdicionais:    "0,00",
+		ValorMaoPropria:       "0,00",
+		ValorAvisoRecebimento: "0,00",
+		ValorValorDeclarado:   "0,00",
+		Erro:                  codigo,
+		MsgErro:               codigo.String(),
+	}
+}
+
+// FreteXML renders the services like the Correios calculator does
+// (ISO-8859-1 encoded XML).
+func FreteXML(svcs ...Servico) []byte {
+	type cServico struct {
+		Codigo                string
+		Valor                 string
+		PrazoEntrega          int
+		ValorSemAdicionais    string
+		ValorMaoPropria       string
+		ValorAvisoRecebimento string
+		ValorValorDeclarado   string
+		EntregaDomiciliar     string
+		EntregaSabado         string
+		Erro                  string
+		MsgErro               string
+		DataMaxEntrega        string `xml:",omitempty"`
+		ObsFim                string `xml:"obsFim"`
+	}
+	sn := func(b bool) string {
+		if b {
+			return "S"
+		}
+		return "N"
+	}
+	list := struct {
+		XMLName  xml.Name   `xml:"Servicos"`
+		Servicos []cServico `xml:"cServico"`
+	}{}
+	for _, v := range svcs {
+		cs := cServico{
+			Codigo:                string(v.Codigo),
+			Valor:                 v.Valor,
+			PrazoEntrega:          v.PrazoEntrega,
+			ValorSemAdicionais:    v.ValorSemAdicionais,
+			ValorMaoPropria:       v.ValorMaoPropria,
+			ValorAvisoRecebimento: v.ValorAvisoRecebimento,
+			ValorValorDeclarado:   v.ValorValorDeclarado,
+			EntregaDomiciliar:     sn(v.EntregaDomiciliar),
+			EntregaSabado:         sn(v.EntregaSabado),
+			Erro:                  "0",
+			MsgErro:               v.MsgErro,
+			DataMaxEntrega:        v.DataMaxEntrega,
+			ObsFim:                v.ObsFim,
+		}
+		if v.Erro != 0 {
+			cs.Erro = erroXML(v.Erro)
+		}
+		list.Servicos = append(list.Servicos, cs)
+	}
+	body, _ := xml.Marshal(list)
+	buf := bytes.NewBufferString(`<?xml version="1.0" encoding="ISO-8859-1" ?>` + "\n")
+	for _, r := range string(body) {
+		if r > 0xFF {
+			r = '?'
+		}
+		buf.WriteByte(byte(r))
+	}
+	return buf.Bytes()
+}
+
+// erroXML formats the positive codes with leading zeros (e.g. "008"), as
+// the Correios do.
+func erroXML(c correios.TipoErro) string {
+	if c > 0 {
+		return fmt.Sprintf("%03d", int(c))
+	}
+	return strconv.Itoa(int(c))
+}
+
+// Server is a fake Correios frete calculator. Unknown service codes are
+// answered with correios.ErrTipoServicoInvalido.
+type Server struct {
+	*httptest.Server
+
+	l        sync.Mutex
+	servicos map[correios.TipoServico]Servico
+	requests []url.Values
+	delay    time.Duration
+}
+
+// NewServer starts a Server that answers the SEDEX and PAC fixtures.
+func NewServer() *Server {
+	s := &Server{
+		servicos: map[correios.TipoServico]Servico{
+			SEDEX.Codigo: SEDEX,
+			PAC.Codigo:   PAC,
+		},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveFrete))
+	return s
+}
+
+// SetServico sets the response of a service code.
+func (s *Server) SetServico(svc Servico) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.servicos[svc.Codigo] = svc
+}
+
+// SetErro makes the service fail with codigo.
+func (s *Server) SetErro(svc correios.TipoServico, codigo correios.TipoErro) {
+	s.SetServico(Erro(svc, codigo))
+}
+
+// SetDelay delays every response by d (or until the request is canceled).
+func (s *Server) SetDelay(d time.Duration) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.delay = d
+}
+
+// Requests returns the query of every request received.
+func (s *Server) Requests() []url.Values {
+	s.l.Lock()
+	defer s.l.Unlock()
+	out := make([]url.Values, len(s.requests))
+	copy(out, s.requests)
+	return out
+}
+
+// Client returns a *correios.Client that sends the frete requests to s.
+func (s *Server) Client() *correios.Client {
+	return &correios.Client{
+		HTTPClient:    s.Server.Client(),
+		FreteEndpoint: s.URL,
+	}
+}
+
+func (s *Server) serveFrete(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.l.Lock()
+	s.requests = append(s.requests, q)
+	delay := s.delay
+	svcs := make([]Servico, 0)
+	for _, v := range strings.Split(q.Get("nCdServico"), ",") {
+		svc, ok := s.servicos[correios.TipoServico(v)]
+		if !ok {
+			svc = Erro(correios.TipoServico(v), correios.ErrTipoServicoInvalido)
+		}
+		svcs = append(svcs, svc)
+	}
+	s.l.Unlock()
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-t.C:
+		}
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=ISO-8859-1")
+	w.Write(FreteXML(svcs...))
+}
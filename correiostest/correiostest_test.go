@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correiostest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/gabstv/correios/correiostest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	r := correios.NewFreteRequest("01243000", "65299970")
+	r.Mode = correios.RequestModeCombined
+	resp, err := srv.Client().CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Servicos, 2)
+	assert.Equal(t, "42.5", resp.Servicos[correios.SvcSEDEXVarejo].Preco.String())
+	assert.Equal(t, 7, resp.Servicos[correios.SvcPACVarejo].PrazoEntregaDias)
+	assert.Len(t, srv.Requests(), 1)
+}
+
+func TestServerErros(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	for _, codigo := range correiostest.CodigosErro {
+		srv.SetErro(correios.SvcSEDEXVarejo, codigo)
+		r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+		resp, err := srv.Client().CalcularFrete(context.Background(), r)
+		assert.NoError(t, err)
+		svc := resp.Servicos[correios.SvcSEDEXVarejo]
+		if assert.NotNil(t, svc.Erro, codigo) {
+			assert.Equal(t, codigo, svc.Erro.Codigo)
+			assert.Equal(t, codigo.String(), svc.ErroMsg)
+		}
+	}
+}
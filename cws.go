@@ -0,0 +1,358 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CWSEndpoint o endpoint da API REST dos Correios (CWS)
+var CWSEndpoint = "https://api.correios.com.br"
+
+// CWSConfig configura o acesso à API REST dos Correios (CWS), que exige um
+// contrato. Quando definido em Client.CWS, CalcularFrete utiliza a API REST
+// e, em caso de falha, o endpoint legado (FreteEndpoint).
+type CWSConfig struct {
+	// Usuario é o usuário do Meu Correios
+	Usuario string
+	// CodigoAcesso é o código de acesso à API gerado no CWS
+	CodigoAcesso string
+	// CartaoPostagem é o número do cartão de postagem do contrato
+	CartaoPostagem string
+	// Endpoint, se vazio, CWSEndpoint
+	Endpoint string
+	// SemFallback desativa o uso do endpoint legado em caso de falha
+	SemFallback bool
+
+	l        sync.Mutex
+	token    string
+	expiraEm time.Time
+}
+
+func (cfg *CWSConfig) endpoint() string {
+	if cfg.Endpoint != "" {
+		return strings.TrimRight(cfg.Endpoint, "/")
+	}
+	return strings.TrimRight(CWSEndpoint, "/")
+}
+
+// cwsError é retornado quando a API REST responde com status != 2xx
+type cwsError struct {
+	StatusCode int
+	Msgs       []string
+}
+
+func (e *cwsError) Error() string {
+	if len(e.Msgs) == 0 {
+		return fmt.Sprintf("correios: cws http status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("correios: cws http status %d: %s", e.StatusCode, strings.Join(e.Msgs, "; "))
+}
+
+// cwsPreco é a resposta do endpoint preco/v1/nacional
+type cwsPreco struct {
+	CoProduto                 string `json:"coProduto"`
+	PcBase                    string `json:"pcBase"`
+	PcProduto                 string `json:"pcProduto"`
+	PcTotalServicosAdicionais string `json:"pcTotalServicosAdicionais"`
+	PcFinal                   string `json:"pcFinal"`
+	ServicoAdicional          []struct {
+		CoServAdicional    string `json:"coServAdicional"`
+		PcServicoAdicional string `json:"pcServicoAdicional"`
+	} `json:"servicoAdicional"`
+	TxErro string `json:"txErro"`
+}
+
+// cwsPrazo é a resposta do endpoint prazo/v1/nacional
+type cwsPrazo struct {
+	CoProduto         string `json:"coProduto"`
+	PrazoEntrega      int    `json:"prazoEntrega"`
+	DataMaxima        string `json:"dataMaxima"`
+	EntregaDomiciliar string `json:"entregaDomiciliar"`
+	EntregaSabado     string `json:"entregaSabado"`
+	TxErro            string `json:"txErro"`
+}
+
+// serviços adicionais da API REST
+const (
+	cwsAvisoRecebimento = "001"
+	cwsMaoPropria       = "002"
+	cwsValorDeclarado   = "019"
+)
+
+// cwsToken retorna o token de acesso, autenticando caso necessário
+func (c *Client) cwsToken(ctx context.Context) (string, error) {
+	cfg := c.CWS
+	cfg.l.Lock()
+	defer cfg.l.Unlock()
+	if cfg.token != "" && time.Now().Add(time.Minute).Before(cfg.expiraEm) {
+		return cfg.token, nil
+	}
+	body, _ := json.Marshal(map[string]string{"numero": cfg.CartaoPostagem})
+	rq0, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.endpoint()+"/token/v1/autentica/cartaopostagem", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	rq0.SetBasicAuth(cfg.Usuario, cfg.CodigoAcesso)
+	rq0.Header.Set("Content-Type", "application/json")
+	rq0.Header.Set("Accept", "application/json")
+	vlov := struct {
+		Token    string `json:"token"`
+		ExpiraEm string `json:"expiraEm"`
+	}{}
+	if err := c.cwsDo(rq0, &vlov); err != nil {
+		return "", err
+	}
+	if vlov.Token == "" {
+		return "", errors.New("correios: cws: token vazio")
+	}
+	cfg.token = vlov.Token
+	cfg.expiraEm, err = time.ParseInLocation("2006-01-02T15:04:05", vlov.ExpiraEm, fusoBrasilia)
+	if err != nil {
+		cfg.expiraEm = time.Now().Add(time.Hour)
+	}
+	return cfg.token, nil
+}
+
+// cwsGet envia um GET autenticado à API REST
+func (c *Client) cwsGet(ctx context.Context, token, path string, q url.Values, out interface{}) error {
+	rq0, err := http.NewRequestWithContext(ctx, http.MethodGet, c.CWS.endpoint()+path+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	rq0.Header.Set("Authorization", "Bearer "+token)
+	rq0.Header.Set("Accept", "application/json")
+	return c.cwsDo(rq0, out)
+}
+
+func (c *Client) cwsDo(rq *http.Request, out interface{}) error {
+	cresp, err := c.do(rq)
+	if err != nil {
+		return &TransporteError{Err: err}
+	}
+	defer cresp.Body.Close()
+	if cresp.StatusCode < 200 || cresp.StatusCode > 299 {
+		e := &cwsError{StatusCode: cresp.StatusCode}
+		msgs := struct {
+			Msgs []string `json:"msgs"`
+		}{}
+		if json.NewDecoder(cresp.Body).Decode(&msgs) == nil {
+			e.Msgs = msgs.Msgs
+		}
+		if e.StatusCode >= 500 {
+			return &TransporteError{Err: e}
+		}
+		return e
+	}
+	if err := json.NewDecoder(cresp.Body).Decode(out); err != nil {
+		return &DecodeError{Err: fmt.Errorf("decode json error: %w", err)}
+	}
+	return nil
+}
+
+// cwsInvalidarToken descarta o token em cache (ex.: revogado antes de
+// expirar), caso ainda não tenha sido renovado por outro request
+func (c *Client) cwsInvalidarToken(token string) {
+	cfg := c.CWS
+	cfg.l.Lock()
+	defer cfg.l.Unlock()
+	if cfg.token == token {
+		cfg.token = ""
+		cfg.expiraEm = time.Time{}
+	}
+}
+
+// calcularFreteCWS calcula o frete utilizando a API REST. Caso o token em
+// cache seja recusado (status 401), um novo token é obtido e o request é
+// repetido uma vez.
+func (c *Client) calcularFreteCWS(ctx context.Context, req *FreteRequest) (*FreteResponse, error) {
+	for tentativa := 1; ; tentativa++ {
+		token, err := c.cwsToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		output, err := c.calcularFreteCWSToken(ctx, req, token)
+		var ce *cwsError
+		if tentativa == 1 && errors.As(err, &ce) && ce.StatusCode == http.StatusUnauthorized {
+			c.cwsInvalidarToken(token)
+			continue
+		}
+		return output, err
+	}
+}
+
+// calcularFreteCWSToken consulta os serviços em paralelo com o token
+func (c *Client) calcularFreteCWSToken(ctx context.Context, req *FreteRequest, token string) (*FreteResponse, error) {
+	output := &FreteResponse{
+		Servicos:  make(map[TipoServico]ServicoResponse),
+		Timestamp: time.Now(),
+		Origem:    OrigemCorreios,
+	}
+	svcs := make([]ServicoResponse, len(req.Servicos))
+	errs := make([]error, len(req.Servicos))
+	wg := sync.WaitGroup{}
+	for i, svc := range req.Servicos {
+		wg.Add(1)
+		go func(i int, svc TipoServico) {
+			defer wg.Done()
+			qpreco, qprazo := cwsValues(req, req.FormatoServico(svc))
+			svcs[i], errs[i] = c.cwsServico(ctx, token, svc, qpreco, qprazo)
+		}(i, svc)
+	}
+	wg.Wait()
+	for i, v := range svcs {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		output.Servicos[v.Tipo] = v
+	}
+	return output, nil
+}
+
+// cwsServico consulta o preço e o prazo de um serviço. Erros de validação
+// (status 4xx) são retornados no ServicoResponse.
+func (c *Client) cwsServico(ctx context.Context, token string, svc TipoServico, qpreco, qprazo url.Values) (ServicoResponse, error) {
+	preco := &cwsPreco{}
+	prazo := &cwsPrazo{}
+	err := c.cwsGet(ctx, token, "/preco/v1/nacional/"+url.PathEscape(string(svc)), qpreco, preco)
+	if err == nil {
+		err = c.cwsGet(ctx, token, "/prazo/v1/nacional/"+url.PathEscape(string(svc)), qprazo, prazo)
+	}
+	if err != nil {
+		var ce *cwsError
+		if errors.As(err, &ce) && ce.StatusCode >= 400 && ce.StatusCode < 500 &&
+			ce.StatusCode != http.StatusUnauthorized && ce.StatusCode != http.StatusForbidden {
+			v := ServicoResponse{
+				Tipo:    svc,
+				Erro:    &ServicoResponseError{Codigo: ErrIndeterminado},
+				ErroMsg: strings.Join(ce.Msgs, "; "),
+			}
+			if v.ErroMsg == "" {
+				v.ErroMsg = ErrIndeterminado.String()
+			}
+			return v, nil
+		}
+		return ServicoResponse{}, err
+	}
+	return cwsServicoResponse(svc, preco, prazo), nil
+}
+
+// cwsValues monta os parâmetros dos endpoints de preço e prazo p/ o formato
+// informado
+func cwsValues(req *FreteRequest, formato Formato) (qpreco, qprazo url.Values) {
+	qprazo = url.Values{}
+	qprazo.Set("cepOrigem", FilterCEP(req.CepOrigem))
+	qprazo.Set("cepDestino", FilterCEP(req.CepDestino))
+	qpreco = url.Values{}
+	qpreco.Set("cepOrigem", FilterCEP(req.CepOrigem))
+	qpreco.Set("cepDestino", FilterCEP(req.CepDestino))
+	qpreco.Set("psObjeto", req.PesoKg.Mul(decimal.NewFromInt(1000)).Round(0).String())
+	switch formato {
+	case FormatoEnvelope:
+		qpreco.Set("tpObjeto", "1")
+	case FormatoRoloCilindro:
+		qpreco.Set("tpObjeto", "3")
+		qpreco.Set("diametro", req.DiametroCm.String())
+	default:
+		qpreco.Set("tpObjeto", "2")
+	}
+	qpreco.Set("comprimento", req.ComprimentoCm.String())
+	qpreco.Set("largura", req.LarguraCm.String())
+	qpreco.Set("altura", req.AlturaCm.String())
+	adicionais := make([]string, 0, 3)
+	if req.AvisoRecebimento {
+		adicionais = append(adicionais, cwsAvisoRecebimento)
+	}
+	if req.MaoPropria {
+		adicionais = append(adicionais, cwsMaoPropria)
+	}
+	if req.ValorDeclarado.IsPositive() {
+		adicionais = append(adicionais, cwsValorDeclarado)
+		qpreco.Set("vlDeclarado", req.ValorDeclarado.String())
+	}
+	if len(adicionais) > 0 {
+		qpreco.Set("servicosAdicionais", strings.Join(adicionais, ","))
+	}
+	return
+}
+
+// cwsData converte a data da API REST (ex.: "2021-06-10T23:59:00") p/ o
+// formato utilizado pelo FreteEndpoint ("10/06/2021"), de modo que
+// DataMaxEntrega não dependa do transporte
+func cwsData(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) < 10 {
+		return v
+	}
+	d, err := time.Parse("2006-01-02", v[:10])
+	if err != nil {
+		return v
+	}
+	return d.Format("02/01/2006")
+}
+
+// cwsServicoResponse converte as respostas da API REST em um ServicoResponse
+func cwsServicoResponse(svc TipoServico, preco *cwsPreco, prazo *cwsPrazo) ServicoResponse {
+	v2 := ServicoResponse{
+		Tipo:                 svc,
+		PrazoEntregaDias:     prazo.PrazoEntrega,
+		EntregaDomiciliar:    (prazo.EntregaDomiciliar == "S"),
+		EntregaSabado:        (prazo.EntregaSabado == "S"),
+		EntregaDomiciliarRaw: prazo.EntregaDomiciliar,
+		EntregaSabadoRaw:     prazo.EntregaSabado,
+		DataMaxEntrega:       cwsData(prazo.DataMaxima),
+	}
+	parse := func(campo, s string) decimal.Decimal {
+		d, err := parseDecimal(s)
+		if err != nil && v2.ParseErr == nil {
+			v2.ParseErr = fmt.Errorf("%s %q: %w", campo, s, err)
+		}
+		return d
+	}
+	v2.Preco = parse("pcFinal", preco.PcFinal)
+	v2.PrecoSemAdicionais = parse("pcProduto", preco.PcProduto)
+	for _, v := range preco.ServicoAdicional {
+		switch v.CoServAdicional {
+		case cwsAvisoRecebimento:
+			v2.PrecoAvisoRecebimento = parse("pcServicoAdicional", v.PcServicoAdicional)
+		case cwsMaoPropria:
+			v2.PrecoMaoPropria = parse("pcServicoAdicional", v.PcServicoAdicional)
+		case cwsValorDeclarado:
+			v2.PrecoValorDeclarado = parse("pcServicoAdicional", v.PcServicoAdicional)
+		}
+	}
+	if msg := strings.TrimSpace(preco.TxErro + " " + prazo.TxErro); msg != "" {
+		v2.Erro = &ServicoResponseError{Codigo: ErrIndeterminado}
+		v2.ErroMsg = msg
+	}
+	return v2
+}
@@ -0,0 +1,236 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCWSServer(t *testing.T, ntoken *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token/v1/autentica/cartaopostagem":
+			*ntoken++
+			u, p, _ := r.BasicAuth()
+			assert.Equal(t, "usuario", u)
+			assert.Equal(t, "codigo", p)
+			body := map[string]string{}
+			json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, "0067599079", body["numero"])
+			w.Write([]byte(`{"token":"tkn","expiraEm":"2099-01-01T00:00:00"}`))
+			return
+		case r.Header.Get("Authorization") != "Bearer tkn":
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		case r.URL.Path == "/preco/v1/nacional/03220":
+			q := r.URL.Query()
+			assert.Equal(t, "01243000", q.Get("cepOrigem"))
+			assert.Equal(t, "500", q.Get("psObjeto"))
+			assert.Equal(t, "2", q.Get("tpObjeto"))
+			w.Write([]byte(`{"coProduto":"03220","pcProduto":"40,10","pcFinal":"42,50",` +
+				`"servicoAdicional":[{"coServAdicional":"002","pcServicoAdicional":"2,40"}]}`))
+		case r.URL.Path == "/prazo/v1/nacional/03220":
+			w.Write([]byte(`{"coProduto":"03220","prazoEntrega":2,"entregaDomiciliar":"S","entregaSabado":"N"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"msgs":["PRC-101: Produto não encontrado"]}`))
+		}
+	}))
+}
+
+func TestClientCWS(t *testing.T) {
+	ntoken := 0
+	srv := newCWSServer(t, &ntoken)
+	defer srv.Close()
+	c := &correios.Client{
+		CWS: &correios.CWSConfig{
+			Usuario:        "usuario",
+			CodigoAcesso:   "codigo",
+			CartaoPostagem: "0067599079",
+			Endpoint:       srv.URL,
+			SemFallback:    true,
+		},
+	}
+	r := correios.NewFreteRequest("01243-000", "65299970").SetServicos("03220", "99999")
+	r.MaoPropria = true
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	s := resp.Servicos["03220"]
+	assert.Nil(t, s.Erro)
+	assert.Equal(t, "42.5", s.Preco.String())
+	assert.Equal(t, "40.1", s.PrecoSemAdicionais.String())
+	assert.Equal(t, "2.4", s.PrecoMaoPropria.String())
+	assert.Equal(t, 2, s.PrazoEntregaDias)
+	assert.True(t, s.EntregaDomiciliar)
+	assert.False(t, s.EntregaSabado)
+	e := resp.Servicos["99999"]
+	assert.NotNil(t, e.Erro)
+	assert.Equal(t, "PRC-101: Produto não encontrado", e.ErroMsg)
+
+	// o token é reaproveitado
+	_, err = c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ntoken)
+}
+
+func TestClientCWSFallback(t *testing.T) {
+	cws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"msgs":["Usuário ou senha inválidos"]}`))
+	}))
+	defer cws.Close()
+	legado := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(freteXML))
+	}))
+	defer legado.Close()
+	c := &correios.Client{
+		FreteEndpoint: legado.URL,
+		CWS: &correios.CWSConfig{
+			Endpoint: cws.URL,
+		},
+	}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, "42.5", resp.Any().Preco.String())
+
+	c.CWS.SemFallback = true
+	_, err = c.CalcularFrete(context.Background(), r)
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "Usuário ou senha inválidos"))
+	}
+}
+
+func TestClientCWSFallbackSeparado(t *testing.T) {
+	var ncws, nlegado int32
+	cws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&ncws, 1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer cws.Close()
+	legado := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&nlegado, 1)
+		w.Write([]byte(freteXML))
+	}))
+	defer legado.Close()
+	c := &correios.Client{
+		FreteEndpoint: legado.URL,
+		CWS:           &correios.CWSConfig{Endpoint: cws.URL},
+	}
+	// sem CdEmpresa: um request legado por serviço, sem novas tentativas
+	// na API REST
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo, correios.SvcPACVarejo)
+	_, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ncws))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&nlegado))
+}
+
+func TestClientCWSTokenRevogado(t *testing.T) {
+	var ntoken int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := fmt.Sprintf("tkn%d", atomic.LoadInt32(&ntoken))
+		switch {
+		case r.URL.Path == "/token/v1/autentica/cartaopostagem":
+			n := atomic.AddInt32(&ntoken, 1)
+			w.Write([]byte(fmt.Sprintf(`{"token":"tkn%d","expiraEm":"2099-01-01T00:00:00"}`, n)))
+		case r.Header.Get("Authorization") != "Bearer "+token:
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/preco/v1/nacional/04014":
+			w.Write([]byte(cwsFixturePreco))
+		case r.URL.Path == "/prazo/v1/nacional/04014":
+			w.Write([]byte(cwsFixturePrazo))
+		}
+	}))
+	defer srv.Close()
+	c := &correios.Client{CWS: &correios.CWSConfig{Endpoint: srv.URL, SemFallback: true}}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	_, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&ntoken))
+
+	// o token é revogado pelo servidor antes de expirar
+	atomic.AddInt32(&ntoken, 1)
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&ntoken))
+	assert.Equal(t, "61.1", resp.Any().Preco.String())
+}
+
+// fixtures equivalentes nos dois formatos (XML do FreteEndpoint e JSON da
+// API REST) p/ o mesmo serviço
+const (
+	cwsFixtureXML = `<?xml version="1.0" encoding="ISO-8859-1" ?><Servicos><cServico>` +
+		`<Codigo>04014</Codigo><Valor>61,10</Valor><PrazoEntrega>3</PrazoEntrega><ValorSemAdicionais>42,50</ValorSemAdicionais>` +
+		`<ValorMaoPropria>7,50</ValorMaoPropria><ValorAvisoRecebimento>6,10</ValorAvisoRecebimento><ValorValorDeclarado>5,00</ValorValorDeclarado>` +
+		`<EntregaDomiciliar>S</EntregaDomiciliar><EntregaSabado>N</EntregaSabado><DataMaxEntrega>10/06/2021</DataMaxEntrega>` +
+		`<Erro>0</Erro><MsgErro></MsgErro></cServico></Servicos>`
+	cwsFixturePreco = `{"coProduto":"04014","pcBase":"42,50","pcProduto":"42,50","pcTotalServicosAdicionais":"18,60","pcFinal":"61,10",` +
+		`"servicoAdicional":[{"coServAdicional":"001","pcServicoAdicional":"6,10"},{"coServAdicional":"002","pcServicoAdicional":"7,50"},` +
+		`{"coServAdicional":"019","pcServicoAdicional":"5,00"}]}`
+	cwsFixturePrazo = `{"coProduto":"04014","prazoEntrega":3,"dataMaxima":"2021-06-10T23:59:00","entregaDomiciliar":"S","entregaSabado":"N"}`
+)
+
+func TestClientCWSMesmaResposta(t *testing.T) {
+	legado := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(cwsFixtureXML))
+	}))
+	defer legado.Close()
+	cws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token/v1/autentica/cartaopostagem":
+			w.Write([]byte(`{"token":"tkn","expiraEm":"2099-01-01T00:00:00"}`))
+		case "/preco/v1/nacional/04014":
+			w.Write([]byte(cwsFixturePreco))
+		case "/prazo/v1/nacional/04014":
+			w.Write([]byte(cwsFixturePrazo))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer cws.Close()
+
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo).SetValorDeclaradoFloat(150)
+	r.MaoPropria = true
+	r.AvisoRecebimento = true
+	xmlResp, err := (&correios.Client{FreteEndpoint: legado.URL}).CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	jsonResp, err := (&correios.Client{CWS: &correios.CWSConfig{Endpoint: cws.URL, SemFallback: true}}).CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+
+	assert.Equal(t, xmlResp.Servicos, jsonResp.Servicos)
+	assert.Equal(t, xmlResp.Origem, jsonResp.Origem)
+	s := jsonResp.Servicos[correios.SvcSEDEXVarejo]
+	assert.Equal(t, "61.1", s.Preco.String())
+	assert.Equal(t, "6.1", s.PrecoAvisoRecebimento.String())
+	assert.Equal(t, "5", s.PrecoValorDeclarado.String())
+	assert.Equal(t, "10/06/2021", s.DataMaxEntrega)
+}
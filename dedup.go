@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"errors"
+)
+
+// Requests idênticos e simultâneos (mesmos parâmetros) compartilham uma
+// única chamada HTTP através de Client.sf (singleflight). A chamada é feita
+// com um context desvinculado dos chamadores (limitado somente por
+// Client.Timeout/GlobalTimeout), de modo que o cancelamento ou o prazo de um
+// chamador não afete os demais; cada chamador aguarda somente até o
+// cancelamento do seu próprio context. A chamada é cancelada quando todos os
+// chamadores desistem.
+
+// voo é o context de uma chamada compartilhada e o número de chamadores
+// aguardando por ela
+type voo struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	n      int
+}
+
+// entrarVoo registra um chamador da chamada key
+func (c *Client) entrarVoo(key string) *voo {
+	c.voosL.Lock()
+	defer c.voosL.Unlock()
+	if c.voos == nil {
+		c.voos = make(map[string]*voo)
+	}
+	v := c.voos[key]
+	if v == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		tctx, tcancel := c.withTimeout(ctx)
+		v = &voo{ctx: tctx, cancel: func() {
+			tcancel()
+			cancel()
+		}}
+		c.voos[key] = v
+	}
+	v.n++
+	return v
+}
+
+// sairVoo remove um chamador; a chamada é cancelada quando não há mais
+// nenhum chamador aguardando
+func (c *Client) sairVoo(key string, v *voo) {
+	c.voosL.Lock()
+	defer c.voosL.Unlock()
+	v.n--
+	if v.n > 0 {
+		return
+	}
+	v.cancel()
+	if c.voos[key] == v {
+		delete(c.voos, key)
+	}
+}
+
+// compartilhado executa fn uma única vez para chamadas simultâneas com a
+// mesma chave. Caso a chamada compartilhada seja interrompida (ex.: todos os
+// seus chamadores desistiram) enquanto o context do chamador ainda é válido,
+// uma nova chamada é feita (uma vez).
+func (c *Client) compartilhado(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, bool, error) {
+	v := c.entrarVoo(key)
+	defer c.sairVoo(key, v)
+	for tentativa := 1; ; tentativa++ {
+		ch := c.sf.DoChan(key, func() (interface{}, error) {
+			return fn(v.ctx)
+		})
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case res := <-ch:
+			if tentativa == 1 && isContextErr(res.Err) && ctx.Err() == nil && v.ctx.Err() == nil {
+				continue
+			}
+			return res.Val, res.Shared, res.Err
+		}
+	}
+}
+
+func isContextErr(err error) bool {
+	return err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+}
+
+// freteCompartilhado executa fn uma única vez para requests de frete
+// simultâneos com a mesma chave
+func (c *Client) freteCompartilhado(ctx context.Context, key string, fn func(ctx context.Context) (*FreteResponse, error)) (*FreteResponse, error) {
+	v, shared, err := c.compartilhado(ctx, "frete:"+key, func(ctx context.Context) (interface{}, error) {
+		return fn(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	r := v.(*FreteResponse)
+	if shared {
+		// cada chamador recebe a sua cópia
+		r = r.clone()
+	}
+	return r, nil
+}
+
+// cepCompartilhado executa fn uma única vez para consultas simultâneas do
+// mesmo CEP
+func (c *Client) cepCompartilhado(ctx context.Context, cep string, fn func(ctx context.Context) (*CEPResult, error)) (*CEPResult, error) {
+	v, _, err := c.compartilhado(ctx, "cep:"+cep, func(ctx context.Context) (interface{}, error) {
+		return fn(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	r := *v.(*CEPResult)
+	return &r, nil
+}
+
+// clone retorna uma cópia de r (o map de serviços não é compartilhado)
+func (r *FreteResponse) clone() *FreteResponse {
+	r2 := *r
+	r2.Servicos = make(map[TipoServico]ServicoResponse, len(r.Servicos))
+	for k, v := range r.Servicos {
+		r2.Servicos[k] = v
+	}
+	return &r2
+}
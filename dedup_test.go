@@ -0,0 +1,140 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gabstv/correios"
+	"github.com/gabstv/correios/correiostest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreteCompartilhado(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	srv.SetDelay(100 * time.Millisecond)
+	c := srv.Client()
+	resps := make([]*correios.FreteResponse, 5)
+	wg := sync.WaitGroup{}
+	for i := range resps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+			resp, err := c.CalcularFrete(context.Background(), r)
+			assert.NoError(t, err)
+			resps[i] = resp
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, srv.Requests(), 1)
+	for _, resp := range resps {
+		assert.Equal(t, "42.5", resp.Any().Preco.String())
+	}
+	// cada chamador recebe a sua cópia
+	delete(resps[0].Servicos, correios.SvcSEDEXVarejo)
+	assert.Len(t, resps[1].Servicos, 1)
+}
+
+func TestCEPCompartilhado(t *testing.T) {
+	var n int32
+	defer withCEPHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(cepJSON))
+	})()
+	c := &correios.Client{}
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := c.ConsultaCEP(context.Background(), "13056-535")
+			assert.NoError(t, err)
+			assert.Equal(t, "Campinas", r.Cidade)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&n))
+}
+
+func TestFreteCompartilhadoPrazos(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	srv.SetDelay(100 * time.Millisecond)
+	c := srv.Client()
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+		_, err := c.CalcularFrete(ctx, r.Clone())
+		assert.Equal(t, context.DeadlineExceeded, err)
+	}()
+	go func() {
+		defer wg.Done()
+		// o prazo do primeiro chamador não afeta o segundo
+		time.Sleep(10 * time.Millisecond)
+		resp, err := c.CalcularFrete(context.Background(), r.Clone())
+		if assert.NoError(t, err) {
+			assert.Equal(t, "42.5", resp.Any().Preco.String())
+		}
+	}()
+	wg.Wait()
+	assert.Len(t, srv.Requests(), 1)
+}
+
+func TestCEPCompartilhadoPrazos(t *testing.T) {
+	var n int32
+	defer withCEPHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(cepJSON))
+	})()
+	c := &correios.Client{}
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(30*time.Millisecond, cancel)
+		_, err := c.ConsultaCEP(ctx, "13056-535")
+		assert.Equal(t, context.Canceled, err)
+	}()
+	go func() {
+		defer wg.Done()
+		time.Sleep(10 * time.Millisecond)
+		r, err := c.ConsultaCEP(context.Background(), "13056-535")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "Campinas", r.Cidade)
+		}
+	}()
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&n))
+}
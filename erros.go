@@ -0,0 +1,360 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrCorreiosIndisponivel é retornado (dentro de um *TransporteError)
+// quando os Correios respondem com uma página HTML em vez do XML esperado,
+// o que ocorre quando o serviço está degradado
+var ErrCorreiosIndisponivel = errors.New("correios: serviço indisponível")
+
+// ErrCredenciais é a categoria dos erros de contrato e credenciais (-34 a
+// -38, ver IsErroCredenciais). Esses erros não são transitórios: o código
+// administrativo, a senha ou o contrato devem ser verificados. Utilize
+// errors.Is(err, ErrCredenciais).
+var ErrCredenciais = errors.New("correios: verifique o contrato e as credenciais (CdEmpresa/DsSenha)")
+
+// ErrFormatosMistos é retornado por CalcularFrete quando RequestModeCombined
+// é utilizado com serviços de formatos diferentes (FreteRequest.Formatos):
+// um único request aceita somente um nCdFormato, então os serviços devem ser
+// consultados separadamente (RequestModeAuto ou RequestModeSingle)
+var ErrFormatosMistos = errors.New("correios: serviços com formatos diferentes não podem ser consultados em um único request")
+
+// descricoesErro contém a descrição (pt-BR) de cada TipoErro conhecido.
+//
+// ErrIndisponivel e ErrLocalidadeDestino compartilham o código 7.
+var descricoesErro = map[TipoErro]string{
+	ErrTipoServicoInvalido:          "Código de serviço inválido",
+	ErrCepOrigemInvalido:            "CEP de origem inválido",
+	ErrCepDestinoInvalido:           "CEP de destino inválido",
+	ErrCepPesoExcedido:              "Peso excedido",
+	ErrValorDeclaradoAlto10k:        "O Valor Declarado não deve exceder R$ 10.000,00",
+	ErrServicoIndisponivelTrecho:    "Serviço indisponível para o trecho informado",
+	ErrValorDeclaradoObrigatorio:    "O Valor Declarado é obrigatório para este serviço",
+	ErrMaoPropriaIndisponivel:       "Serviço de Mão Própria não disponível",
+	ErrAvisoRecebimentoIndisponivel: "Serviço de Aviso de Recebimento não disponível",
+	ErrPrecificacaoIndisponivel:     "Precificação indisponível para o trecho informado",
+	ErrInformarDimensoes:            "Para definição do preço deverão ser informados, também, o comprimento, a largura e altura do objeto em centímetros (cm)",
+	ErrComprimento:                  "Comprimento inválido",
+	ErrLargura:                      "Largura inválida",
+	ErrAltura:                       "Altura inválida",
+	ErrComprimento105:               "O comprimento não pode ser maior que 105 cm",
+	ErrLargura105:                   "A largura não pode ser maior que 105 cm",
+	ErrAltura105:                    "A altura não pode ser maior que 105 cm",
+	ErrAlturaInferior:               "A altura não pode ser inferior a 2 cm",
+	ErrLarguraInferior:              "A largura não pode ser inferior a 11 cm",
+	ErrComprimentoInferior:          "O comprimento não pode ser inferior a 16 cm",
+	ErrDimensoesSoma:                "A soma resultante do comprimento + largura + altura não deve superar a 200 cm",
+	ErrComprimento2:                 "Comprimento inválido",
+	ErrDiametro:                     "Diâmetro inválido",
+	ErrComprimento3:                 "Comprimento inválido",
+	ErrDiametro2:                    "Diâmetro inválido",
+	ErrComprimento4:                 "O comprimento não pode ser maior que 105 cm",
+	ErrDiametro91:                   "O diâmetro não pode ser maior que 91 cm",
+	ErrComprimento18:                "O comprimento não pode ser inferior a 18 cm",
+	ErrDiametro5:                    "O diâmetro não pode ser inferior a 5 cm",
+	ErrSomaDiametro:                 "A soma resultante do comprimento + o dobro do diâmetro não deve superar a 200 cm",
+	ErrSistemaIndisponivel:          "Sistema temporariamente fora do ar. Favor tentar mais tarde",
+	ErrCodigoOuSenha:                "Código Administrativo ou Senha inválidos",
+	ErrSenha:                        "Senha incorreta",
+	ErrSemContrato:                  "Cliente não possui contrato vigente com os Correios",
+	ErrSemServicoAtivo:              "Cliente não possui serviço ativo em seu contrato",
+	ErrServicoIndisponivelAdmin:     "Serviço indisponível para este código administrativo",
+	ErrPesoExcedidoEnvelope:         "Peso excedido para o formato envelope",
+	ErrInformarDimensoes2:           "Para definição do preço deverão ser informados, também, o comprimento, a largura e altura do objeto em centímetros (cm)",
+	ErrComprimento60:                "O comprimento não pode ser maior que 60 cm",
+	ErrComprimento16:                "O comprimento não pode ser inferior a 16 cm",
+	ErrComprimentoLargura120:        "A soma resultante do comprimento + largura não deve superar a 120 cm",
+	ErrLarguraInferior2:             "A largura não pode ser inferior a 11 cm",
+	ErrLarguraSuperior60:            "A largura não pode ser maior que 60 cm",
+	ErrErroCalculoTarifa:            "Erro ao calcular a tarifa",
+	ErrLocalidadeOrigem:             "Localidade de origem não abrange o serviço informado",
+	ErrLocalidadeDestino:            "Localidade de destino não abrange o serviço informado ou serviço indisponível",
+	ErrServicoIndisponivelTrecho2:   "Serviço indisponível para o trecho informado",
+	ErrAreaDeRiscoCEPInicial:        "CEP inicial pertencente a Área de Risco",
+	ErrAreaPrazoDiferenciado:        "Área com entrega temporariamente sujeita a prazo diferenciado",
+	ErrAreaDeRiscoCEPs:              "CEP inicial e final pertencentes a Área de Risco",
+	ErrIndeterminado:                "Erro indeterminado",
+}
+
+// String retorna a descrição do erro
+func (c TipoErro) String() string {
+	if d, ok := descricoesErro[c]; ok {
+		return d
+	}
+	return fmt.Sprintf("erro desconhecido (%d)", int(c))
+}
+
+// descricoesErroEN contém a descrição em inglês dos erros mais comuns (ver
+// DescricaoLocale)
+var descricoesErroEN = map[TipoErro]string{
+	ErrTipoServicoInvalido:          "Invalid service code",
+	ErrCepOrigemInvalido:            "Invalid origin ZIP code",
+	ErrCepDestinoInvalido:           "Invalid destination ZIP code",
+	ErrCepPesoExcedido:              "Weight limit exceeded",
+	ErrValorDeclaradoAlto10k:        "The declared value must not exceed R$ 10,000.00",
+	ErrServicoIndisponivelTrecho:    "Service unavailable for the given route",
+	ErrValorDeclaradoObrigatorio:    "A declared value is required for this service",
+	ErrMaoPropriaIndisponivel:       "In-hand delivery (Mão Própria) is not available",
+	ErrAvisoRecebimentoIndisponivel: "Delivery receipt (Aviso de Recebimento) is not available",
+	ErrPrecificacaoIndisponivel:     "Pricing unavailable for the given route",
+	ErrInformarDimensoes:            "Length, width and height (cm) are required to calculate the price",
+	ErrComprimento105:               "Length must not exceed 105 cm",
+	ErrLargura105:                   "Width must not exceed 105 cm",
+	ErrAltura105:                    "Height must not exceed 105 cm",
+	ErrAlturaInferior:               "Height must be at least 2 cm",
+	ErrLarguraInferior:              "Width must be at least 11 cm",
+	ErrComprimentoInferior:          "Length must be at least 16 cm",
+	ErrDimensoesSoma:                "Length + width + height must not exceed 200 cm",
+	ErrSomaDiametro:                 "Length + twice the diameter must not exceed 200 cm",
+	ErrSistemaIndisponivel:          "System temporarily unavailable, please try again later",
+	ErrCodigoOuSenha:                "Invalid administrative code or password",
+	ErrSenha:                        "Incorrect password",
+	ErrSemContrato:                  "Customer has no active contract with Correios",
+	ErrSemServicoAtivo:              "Customer has no active service in the contract",
+	ErrServicoIndisponivelAdmin:     "Service unavailable for this administrative code",
+	ErrPesoExcedidoEnvelope:         "Weight limit exceeded for the envelope format",
+	ErrErroCalculoTarifa:            "Error calculating the fare",
+	ErrLocalidadeOrigem:             "Origin location is not covered by the service",
+	ErrLocalidadeDestino:            "Destination location is not covered by the service or the service is unavailable",
+	ErrServicoIndisponivelTrecho2:   "Service unavailable for the given route",
+	ErrAreaPrazoDiferenciado:        "Area temporarily subject to a different delivery time",
+	ErrIndeterminado:                "Undetermined error",
+}
+
+// DescricaoLocale retorna a descrição do erro no idioma lang (ex.: "en",
+// "en-US", "pt-BR"). Idiomas sem tradução, ou códigos sem descrição no
+// idioma informado, utilizam a descrição em pt-BR (String).
+func DescricaoLocale(c TipoErro, lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "en" || strings.HasPrefix(lang, "en-") || strings.HasPrefix(lang, "en_") {
+		if d, ok := descricoesErroEN[c]; ok {
+			return d
+		}
+	}
+	return c.String()
+}
+
+// Error implementa a interface error
+func (e *ServicoResponseError) Error() string {
+	if d, ok := descricoesErro[e.Codigo]; ok {
+		if IsErroCredenciais(e.Codigo) {
+			return fmt.Sprintf("correios: %s (%d): verifique o contrato e as credenciais", d, int(e.Codigo))
+		}
+		return fmt.Sprintf("correios: %s (%d)", d, int(e.Codigo))
+	}
+	return "correios: " + e.Codigo.String()
+}
+
+// Is permite utilizar errors.Is(err, ErrCredenciais)
+func (e *ServicoResponseError) Is(target error) bool {
+	return target == ErrCredenciais && IsErroCredenciais(e.Codigo)
+}
+
+// IsErroCredenciais informa se o erro c indica um problema de contrato ou
+// credenciais (código administrativo ou senha inválidos, contrato inativo,
+// serviço fora do contrato), que não deve ser repetido (ver ErrCredenciais)
+func IsErroCredenciais(c TipoErro) bool {
+	switch c {
+	case ErrCodigoOuSenha, ErrSenha, ErrSemContrato, ErrSemServicoAtivo, ErrServicoIndisponivelAdmin:
+		return true
+	}
+	return false
+}
+
+// IsRetryable informa se o erro c é transitório, ou seja, se uma nova
+// tentativa pode ter sucesso. Erros de validação e de credenciais (ver
+// IsErroCredenciais) retornam false.
+func IsRetryable(c TipoErro) bool {
+	switch c {
+	case ErrSistemaIndisponivel, ErrIndisponivel, ErrErroCalculoTarifa, ErrAreaPrazoDiferenciado:
+		return true
+	}
+	return false
+}
+
+// SemCobertura informa se o erro do serviço indica que ele não é oferecido
+// para o trecho (códigos -6, 006, 007 e 008), um resultado normal e não uma
+// falha. O código 7 também é utilizado pelos Correios para "serviço
+// indisponível, tente mais tarde" (ErrIndisponivel); neste caso, a mensagem
+// é utilizada para diferenciar.
+func (s ServicoResponse) SemCobertura() bool {
+	if s.Erro == nil {
+		return false
+	}
+	switch s.Erro.Codigo {
+	case ErrServicoIndisponivelTrecho, ErrLocalidadeOrigem, ErrServicoIndisponivelTrecho2:
+		return true
+	case ErrLocalidadeDestino:
+		return !strings.Contains(strings.ToLower(s.ErroMsg), "tente mais tarde")
+	}
+	return false
+}
+
+// transitorio informa se o erro do serviço é transitório (IsRetryable),
+// desconsiderando o código 7 quando indica um trecho sem cobertura (ver
+// SemCobertura) e o código 010, que acompanha um preço válido
+func (s ServicoResponse) transitorio() bool {
+	if s.Erro == nil || s.Erro.Codigo == ErrAreaPrazoDiferenciado || s.SemCobertura() {
+		return false
+	}
+	return IsRetryable(s.Erro.Codigo)
+}
+
+// ServicosError é retornado por (*FreteResponse).Err quando nenhum serviço
+// foi precificado com sucesso. Também é retornado por CalcularFrete, junto
+// com os serviços já consultados, quando o context é cancelado durante
+// requests separados por serviço; nesse caso Erros contém somente os
+// serviços interrompidos e Err é o erro do context.
+type ServicosError struct {
+	Erros map[TipoServico]*ServicoResponseError
+	Err   error
+}
+
+func (e *ServicosError) tipos() []TipoServico {
+	tipos := make([]TipoServico, 0, len(e.Erros))
+	for k := range e.Erros {
+		tipos = append(tipos, k)
+	}
+	sort.Slice(tipos, func(i, j int) bool { return tipos[i] < tipos[j] })
+	return tipos
+}
+
+// Error implementa a interface error
+func (e *ServicosError) Error() string {
+	if len(e.Erros) == 0 {
+		return "correios: nenhum serviço encontrado"
+	}
+	msgs := make([]string, 0, len(e.Erros))
+	for _, k := range e.tipos() {
+		msgs = append(msgs, string(k)+": "+e.Erros[k].Codigo.String())
+	}
+	if e.Err != nil {
+		return "correios: consulta interrompida (" + strings.Join(msgs, "; ") + "): " + e.Err.Error()
+	}
+	return "correios: nenhum serviço disponível (" + strings.Join(msgs, "; ") + ")"
+}
+
+// erros retorna Err (se definido) e os erros dos serviços, ordenados pelo
+// código do serviço
+func (e *ServicosError) erros() []error {
+	if len(e.Erros) == 0 && e.Err == nil {
+		return []error{&ServicoResponseError{Codigo: ErrIndeterminado}}
+	}
+	errs := make([]error, 0, len(e.Erros)+1)
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	for _, k := range e.tipos() {
+		errs = append(errs, e.Erros[k])
+	}
+	return errs
+}
+
+// Is verifica o erro de todos os serviços, permitindo utilizar
+// errors.Is(err, ErrCredenciais) mesmo que somente um deles seja de
+// credenciais
+func (e *ServicosError) Is(target error) bool {
+	for _, err := range e.erros() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As verifica o erro de todos os serviços (em ordem de código), permitindo
+// utilizar errors.As(err, &*ServicoResponseError)
+func (e *ServicosError) As(target interface{}) bool {
+	for _, err := range e.erros() {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// CampoInvalidoError é retornado por FreteRequestFromValues quando um
+// parâmetro não pode ser interpretado. Err é o erro original.
+type CampoInvalidoError struct {
+	Campo string
+	Valor string
+	Err   error
+}
+
+// Error implementa a interface error
+func (e *CampoInvalidoError) Error() string {
+	return fmt.Sprintf("correios: %s: valor inválido %q: %v", e.Campo, e.Valor, e.Err)
+}
+
+// Unwrap retorna o erro original
+func (e *CampoInvalidoError) Unwrap() error {
+	return e.Err
+}
+
+// TransporteError é retornado por CalcularFrete quando os Correios não
+// puderam ser consultados (falha de rede, timeout ou status HTTP != 200).
+// Err é o erro original.
+type TransporteError struct {
+	Err error
+}
+
+// Error implementa a interface error
+func (e *TransporteError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap retorna o erro original
+func (e *TransporteError) Unwrap() error {
+	return e.Err
+}
+
+// StatusCode retorna o status HTTP da resposta, ou 0 caso o erro não seja
+// de status (ex.: falha de rede)
+func (e *TransporteError) StatusCode() int {
+	var se *httpStatusError
+	if errors.As(e.Err, &se) {
+		return se.StatusCode
+	}
+	return 0
+}
+
+// DecodeError é retornado por CalcularFrete quando a resposta dos Correios
+// não pôde ser interpretada. Err é o erro original.
+type DecodeError struct {
+	Err error
+}
+
+// Error implementa a interface error
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap retorna o erro original
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServicoResponseError(t *testing.T) {
+	var err error = &correios.ServicoResponseError{Codigo: correios.ErrCepDestinoInvalido}
+	assert.Equal(t, "correios: CEP de destino inválido (-3)", err.Error())
+	var se *correios.ServicoResponseError
+	assert.True(t, errors.As(err, &se))
+	assert.Equal(t, correios.ErrCepDestinoInvalido, se.Codigo)
+	err = &correios.ServicoResponseError{Codigo: correios.TipoErro(-12345)}
+	assert.Equal(t, "correios: erro desconhecido (-12345)", err.Error())
+}
+
+func TestTipoErroString(t *testing.T) {
+	assert.Equal(t, "Senha incorreta", correios.ErrSenha.String())
+	assert.Equal(t, "Erro ao calcular a tarifa", correios.ErrErroCalculoTarifa.String())
+	assert.Equal(t, "erro desconhecido (-999)", correios.TipoErro(-999).String())
+}
+
+func TestDescricaoLocale(t *testing.T) {
+	assert.Equal(t, "Invalid origin ZIP code", correios.DescricaoLocale(correios.ErrCepOrigemInvalido, "en"))
+	assert.Equal(t, "Invalid origin ZIP code", correios.DescricaoLocale(correios.ErrCepOrigemInvalido, "en-US"))
+	assert.Equal(t, "CEP de origem inválido", correios.DescricaoLocale(correios.ErrCepOrigemInvalido, "pt-BR"))
+	assert.Equal(t, "CEP de origem inválido", correios.DescricaoLocale(correios.ErrCepOrigemInvalido, ""))
+	// sem tradução: pt-BR
+	assert.Equal(t, correios.ErrDiametro91.String(), correios.DescricaoLocale(correios.ErrDiametro91, "en"))
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, correios.IsRetryable(correios.ErrSistemaIndisponivel))
+	assert.True(t, correios.IsRetryable(correios.ErrIndisponivel))
+	assert.True(t, correios.IsRetryable(correios.ErrErroCalculoTarifa))
+	assert.True(t, correios.IsRetryable(correios.ErrAreaPrazoDiferenciado))
+	assert.False(t, correios.IsRetryable(correios.ErrCepDestinoInvalido))
+	assert.False(t, correios.IsRetryable(correios.ErrComprimento105))
+}
+
+func TestErroCredenciais(t *testing.T) {
+	for _, c := range []correios.TipoErro{correios.ErrCodigoOuSenha, correios.ErrSenha,
+		correios.ErrSemContrato, correios.ErrSemServicoAtivo, correios.ErrServicoIndisponivelAdmin} {
+		assert.True(t, correios.IsErroCredenciais(c), c)
+		assert.False(t, correios.IsRetryable(c), c)
+	}
+	assert.False(t, correios.IsErroCredenciais(correios.ErrSistemaIndisponivel))
+
+	var err error = &correios.ServicoResponseError{Codigo: correios.ErrSenha}
+	assert.True(t, errors.Is(err, correios.ErrCredenciais))
+	assert.Equal(t, "correios: Senha incorreta (-35): verifique o contrato e as credenciais", err.Error())
+	assert.False(t, errors.Is(&correios.ServicoResponseError{Codigo: correios.ErrCepDestinoInvalido}, correios.ErrCredenciais))
+
+	resp := &correios.FreteResponse{Servicos: map[correios.TipoServico]correios.ServicoResponse{
+		correios.SvcPACComContrato: {Tipo: correios.SvcPACComContrato, Erro: &correios.ServicoResponseError{Codigo: correios.ErrSemContrato}},
+	}}
+	assert.True(t, errors.Is(resp.Err(), correios.ErrCredenciais))
+}
+
+func TestTipoErroValores(t *testing.T) {
+	// os códigos positivos são retornados com zeros à esquerda (ex.: 010),
+	// mas devem ser interpretados como decimais
+	assert.Equal(t, 6, int(correios.ErrLocalidadeOrigem))
+	assert.Equal(t, 7, int(correios.ErrLocalidadeDestino))
+	assert.Equal(t, 7, int(correios.ErrIndisponivel))
+	assert.Equal(t, 8, int(correios.ErrServicoIndisponivelTrecho2))
+	assert.Equal(t, 9, int(correios.ErrAreaDeRiscoCEPInicial))
+	assert.Equal(t, 10, int(correios.ErrAreaPrazoDiferenciado))
+	assert.Equal(t, 11, int(correios.ErrAreaDeRiscoCEPs))
+	assert.Equal(t, 99, int(correios.ErrIndeterminado))
+	assert.Equal(t, -888, int(correios.ErrErroCalculoTarifa))
+}
+
+func TestErrLargura(t *testing.T) {
+	assert.Equal(t, -44, int(correios.ErrLarguraInferior2))
+	assert.Equal(t, -45, int(correios.ErrLarguraSuperior60))
+	assert.Equal(t, "A largura não pode ser inferior a 11 cm", correios.ErrLarguraInferior2.String())
+	assert.Equal(t, "A largura não pode ser maior que 60 cm", correios.ErrLarguraSuperior60.String())
+}
+
+func TestFreteResponseErr(t *testing.T) {
+	resp := &correios.FreteResponse{
+		Servicos: map[correios.TipoServico]correios.ServicoResponse{
+			correios.SvcSEDEXVarejo: {
+				Tipo: correios.SvcSEDEXVarejo,
+				Erro: &correios.ServicoResponseError{Codigo: correios.ErrCepDestinoInvalido},
+			},
+			correios.SvcPACVarejo: {
+				Tipo: correios.SvcPACVarejo,
+				Erro: &correios.ServicoResponseError{Codigo: correios.ErrSistemaIndisponivel},
+			},
+		},
+	}
+	err := resp.Err()
+	assert.EqualError(t, err, "correios: nenhum serviço disponível (04014: CEP de destino inválido; 04510: Sistema temporariamente fora do ar. Favor tentar mais tarde)")
+	var se *correios.ServicoResponseError
+	assert.True(t, errors.As(err, &se))
+	assert.Equal(t, correios.ErrCepDestinoInvalido, se.Codigo)
+	var sse *correios.ServicosError
+	assert.True(t, errors.As(err, &sse))
+	assert.Len(t, sse.Erros, 2)
+	assert.False(t, errors.Is(err, correios.ErrCredenciais))
+
+	// o erro de credenciais não é o do serviço de menor código
+	resp.Servicos[correios.SvcPACVarejo].Erro.Codigo = correios.ErrSemContrato
+	err = resp.Err()
+	assert.True(t, errors.Is(err, correios.ErrCredenciais))
+	var ce *correios.CampoInvalidoError
+	assert.False(t, errors.As(err, &ce))
+
+	resp.Servicos[correios.SvcSEDEX10Varejo] = correios.ServicoResponse{Tipo: correios.SvcSEDEX10Varejo}
+	assert.NoError(t, resp.Err())
+	assert.Error(t, (&correios.FreteResponse{}).Err())
+}
+
+func TestSemCobertura(t *testing.T) {
+	s := correios.ServicoResponse{}
+	assert.False(t, s.SemCobertura())
+	s.Erro = &correios.ServicoResponseError{Codigo: correios.ErrLocalidadeOrigem}
+	assert.True(t, s.SemCobertura())
+	s.Erro.Codigo = correios.ErrLocalidadeDestino
+	s.ErroMsg = "Localidade de destino não abrange o serviço informado"
+	assert.True(t, s.SemCobertura())
+	s.ErroMsg = "Serviço indisponível, tente mais tarde"
+	assert.False(t, s.SemCobertura())
+	s.Erro.Codigo = correios.ErrCepDestinoInvalido
+	assert.False(t, s.SemCobertura())
+}
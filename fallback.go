@@ -0,0 +1,53 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+// FallbackFunc, se definida, é chamada por CalcularFrete quando o request
+// aos Correios falha (erro de rede, status != 200 ou erro de decodificação).
+// Os parâmetros v são exatamente os que seriam enviados ao FreteEndpoint e
+// ctx é o context do request (incluindo o deadline).
+var FallbackFunc func(ctx context.Context, v url.Values) (*FreteResponse, error)
+
+// AlwaysUseFallback faz com que CalcularFrete utilize FallbackFunc
+// diretamente, sem consultar o FreteEndpoint.
+var AlwaysUseFallback bool
+
+// GlobalTimeout, se > 0, é aplicado às chamadas de CalcularFrete e
+// ConsultaCEP cujo context não possua um deadline.
+var GlobalTimeout time.Duration
+
+// withTimeout deriva um context com o timeout d caso d > 0 e ctx não possua
+// um deadline.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"time"
+)
+
+// FeriadoFunc informa se a data d é um feriado (dia sem entregas)
+type FeriadoFunc func(d time.Time) bool
+
+// Feriado é utilizado por DataEntregaEstimada para ignorar os feriados na
+// contagem dos dias úteis. Se nil, somente os fins de semana são
+// ignorados. Ex.: correios.Feriado = correios.FeriadoNacional
+var Feriado FeriadoFunc
+
+// Feriados retorna uma FeriadoFunc a partir de uma lista de datas (somente
+// o dia é considerado, não o horário), útil p/ feriados regionais
+func Feriados(datas ...time.Time) FeriadoFunc {
+	m := make(map[[3]int]bool, len(datas))
+	for _, d := range datas {
+		m[dia(d)] = true
+	}
+	return func(d time.Time) bool {
+		return m[dia(d)]
+	}
+}
+
+// FeriadoNacional informa se d é um feriado nacional ou um dia sem
+// entregas em todo o país (ver FeriadosNacionais)
+func FeriadoNacional(d time.Time) bool {
+	for _, f := range FeriadosNacionais(d.Year()) {
+		if dia(f) == dia(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// FeriadosNacionais retorna os feriados nacionais do ano, incluindo o
+// Carnaval (segunda e terça), a Sexta-feira Santa e Corpus Christi, em que
+// não há entregas
+func FeriadosNacionais(ano int) []time.Time {
+	data := func(mes time.Month, d int) time.Time {
+		return time.Date(ano, mes, d, 0, 0, 0, 0, time.UTC)
+	}
+	pascoa := domingoDePascoa(ano)
+	list := []time.Time{
+		data(time.January, 1),     // Confraternização Universal
+		pascoa.AddDate(0, 0, -48), // Carnaval (segunda)
+		pascoa.AddDate(0, 0, -47), // Carnaval (terça)
+		pascoa.AddDate(0, 0, -2),  // Sexta-feira Santa
+		data(time.April, 21),      // Tiradentes
+		data(time.May, 1),         // Dia do Trabalho
+		pascoa.AddDate(0, 0, 60),  // Corpus Christi
+		data(time.September, 7),   // Independência
+		data(time.October, 12),    // Nossa Senhora Aparecida
+		data(time.November, 2),    // Finados
+		data(time.November, 15),   // Proclamação da República
+	}
+	if ano >= 2024 {
+		list = append(list, data(time.November, 20)) // Consciência Negra
+	}
+	return append(list, data(time.December, 25)) // Natal
+}
+
+// domingoDePascoa calcula a data da Páscoa (algoritmo de Meeus/Jones/Butcher)
+func domingoDePascoa(ano int) time.Time {
+	a := ano % 19
+	b := ano / 100
+	c := ano % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	mes := (h + l - 7*m + 114) / 31
+	d2 := (h+l-7*m+114)%31 + 1
+	return time.Date(ano, time.Month(mes), d2, 0, 0, 0, 0, time.UTC)
+}
+
+func dia(d time.Time) [3]int {
+	return [3]int{d.Year(), int(d.Month()), d.Day()}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeriadosNacionais(t *testing.T) {
+	data := func(ano int, mes time.Month, d int) time.Time {
+		return time.Date(ano, mes, d, 15, 0, 0, 0, time.UTC)
+	}
+	// Carnaval de 2022: 28/02 e 01/03; Páscoa em 17/04
+	assert.True(t, correios.FeriadoNacional(data(2022, time.February, 28)))
+	assert.True(t, correios.FeriadoNacional(data(2022, time.March, 1)))
+	assert.True(t, correios.FeriadoNacional(data(2022, time.April, 15)))
+	assert.True(t, correios.FeriadoNacional(data(2022, time.June, 16)))
+	assert.True(t, correios.FeriadoNacional(data(2022, time.December, 25)))
+	assert.False(t, correios.FeriadoNacional(data(2022, time.March, 2)))
+	assert.False(t, correios.FeriadoNacional(data(2023, time.November, 20)))
+	assert.True(t, correios.FeriadoNacional(data(2024, time.November, 20)))
+
+	// sexta-feira antes do Carnaval
+	s := correios.ServicoResponse{PrazoEntregaDias: 2}
+	from := data(2022, time.February, 25)
+	assert.Equal(t, data(2022, time.March, 3), s.DataEntregaEstimadaFeriados(from, correios.FeriadoNacional))
+	assert.Equal(t, data(2022, time.March, 1), s.DataEntregaEstimada(from))
+	correios.Feriado = correios.Feriados(data(2022, time.February, 28))
+	defer func() { correios.Feriado = nil }()
+	assert.Equal(t, data(2022, time.March, 2), s.DataEntregaEstimada(from))
+}
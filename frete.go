@@ -30,7 +30,11 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -47,8 +51,27 @@ const (
 	RequestModeCombined
 )
 
-// FreteEndpoint o endpoint a ser utilizado para calcular o frete
-var FreteEndpoint = "http://ws.correios.com.br/calculador/CalcPrecoPrazo.aspx"
+// Formato representa o formato da encomenda (nCdFormato)
+type Formato int
+
+const (
+	// FormatoCaixaPacote caixa ou pacote
+	FormatoCaixaPacote Formato = 1
+	// FormatoRoloCilindro rolo ou prisma
+	FormatoRoloCilindro Formato = 2
+	// FormatoEnvelope envelope
+	FormatoEnvelope Formato = 3
+)
+
+// FreteEndpoint o endpoint a ser utilizado para calcular o frete.
+// Utiliza https para que nCdEmpresa e sDsSenha não trafeguem em texto puro;
+// para voltar ao endpoint http, basta alterar esta variável (ou
+// Client.FreteEndpoint).
+var FreteEndpoint = "https://ws.correios.com.br/calculador/CalcPrecoPrazo.aspx"
+
+// FreteUserAgent o User-Agent enviado nas consultas de frete e prazo. Os
+// Correios bloqueiam (403) o User-Agent padrão do Go.
+var FreteUserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1 Safari/605.1.15"
 
 // TipoServico representa os tipos de serviço (numérico)
 type TipoServico string
@@ -75,26 +98,20 @@ const (
 	SvcSEDEXComContrato   TipoServico = "04162"
 	SvcPACVarejo          TipoServico = "04510"
 	SvcPACComContrato     TipoServico = "04669"
+	// SvcSEDEX12Varejo entrega até as 12h do dia útil seguinte (somente em
+	// algumas localidades)
+	SvcSEDEX12Varejo TipoServico = "40169"
+	// SvcMiniEnvios é destinado a objetos pequenos e de baixo valor; exige
+	// contrato (CdEmpresa e DsSenha)
+	SvcMiniEnvios TipoServico = "04227"
 )
 
+// Carta Registrada não é precificada pelo calculador remoto (somente
+// encomendas), por isso não há uma constante para o serviço.
+
+// String retorna o nome do serviço (ver ServicoNome)
 func (svct TipoServico) String() string {
-	switch svct {
-	case SvcSEDEXVarejo:
-		return "SEDEX Varejo"
-	case SvcSEDEXACobrarVarejo:
-		return "SEDEX Varejo (a cobrar)"
-	case SvcSEDEX10Varejo:
-		return "SEDEX 10 Varejo"
-	case SvcSEDEXHojeVarejo:
-		return "SEDEX Hoje Varejo"
-	case SvcSEDEXComContrato:
-		return "SEDEX"
-	case SvcPACVarejo:
-		return "PAC Varejo"
-	case SvcPACComContrato:
-		return "PAC"
-	}
-	return string(svct)
+	return ServicoNome(svct)
 }
 
 // Todos os tipos de erros possíveis que a API dos Correios pode retornar
@@ -140,8 +157,8 @@ const (
 	ErrComprimento60                TipoErro = -41  // O comprimento nao pode ser maior que 60 cm.
 	ErrComprimento16                TipoErro = -42  // (repetido) O comprimento nao pode ser inferior a 16 cm.
 	ErrComprimentoLargura120        TipoErro = -43  // A soma resultante do comprimento + largura nao deve superar a 120 cm
-	ErrLarguraInferior2             TipoErro = -44  // < 11cm
-	ErrLarguraSuperior60            TipoErro = -44  // > 60cm
+	ErrLarguraInferior2             TipoErro = -44  // A largura nao pode ser inferior a 11 cm.
+	ErrLarguraSuperior60            TipoErro = -45  // A largura nao pode ser maior que 60 cm.
 	ErrErroCalculoTarifa            TipoErro = -888 // Erro ao calcular a tarifa
 	ErrLocalidadeOrigem             TipoErro = 6    // Localidade de origem não abrange o serviço informado
 	ErrLocalidadeDestino            TipoErro = 7    // Localidade de destino não abrange o serviço informado
@@ -154,18 +171,121 @@ const (
 )
 
 type FreteRequest struct {
-	CepOrigem        string
-	CepDestino       string
-	PesoKg           decimal.Decimal
-	ComprimentoCm    decimal.Decimal
-	AlturaCm         decimal.Decimal
-	LarguraCm        decimal.Decimal
+	CepOrigem     string
+	CepDestino    string
+	PesoKg        decimal.Decimal
+	ComprimentoCm decimal.Decimal
+	AlturaCm      decimal.Decimal
+	LarguraCm     decimal.Decimal
+	DiametroCm    decimal.Decimal // somente p/ FormatoRoloCilindro
+	Formato       Formato
+	// Formatos sobrescreve Formato p/ serviços específicos (ex.: envelope
+	// p/ um serviço e caixa p/ outro). Serviços com formatos diferentes são
+	// consultados em requests separados (ver FormatoServico).
+	Formatos         map[TipoServico]Formato
 	Servicos         []TipoServico
 	ValorDeclarado   decimal.Decimal
 	AvisoRecebimento bool
+	MaoPropria       bool
 	CdEmpresa        string
 	DsSenha          string
 	Mode             RequestMode
+	// IgnorarCache força uma nova consulta mesmo que a resposta esteja no
+	// Client.FreteCache; o cache é atualizado com o resultado
+	IgnorarCache bool
+}
+
+// Clone retorna uma cópia de r
+func (r *FreteRequest) Clone() *FreteRequest {
+	r2 := *r
+	if r.Servicos != nil {
+		r2.Servicos = make([]TipoServico, len(r.Servicos))
+		copy(r2.Servicos, r.Servicos)
+	}
+	if r.Formatos != nil {
+		r2.Formatos = make(map[TipoServico]Formato, len(r.Formatos))
+		for k, v := range r.Formatos {
+			r2.Formatos[k] = v
+		}
+	}
+	return &r2
+}
+
+// FormatoServico retorna o formato utilizado p/ o serviço svc: Formatos[svc]
+// ou, se ausente, Formato
+func (r *FreteRequest) FormatoServico(svc TipoServico) Formato {
+	if f, ok := r.Formatos[svc]; ok {
+		return f
+	}
+	return r.Formato
+}
+
+// formatoEfetivo retorna o formato do primeiro serviço (ver FormatoServico)
+func (r *FreteRequest) formatoEfetivo() Formato {
+	if len(r.Servicos) > 0 {
+		return r.FormatoServico(r.Servicos[0])
+	}
+	return r.Formato
+}
+
+// formatosMistos informa se os serviços do request utilizam formatos
+// diferentes
+func (r *FreteRequest) formatosMistos() bool {
+	f := r.formatoEfetivo()
+	for _, svc := range r.Servicos {
+		if r.FormatoServico(svc) != f {
+			return true
+		}
+	}
+	return false
+}
+
+// String retorna um resumo do request p/ logs, sem a senha (DsSenha). Ex.:
+//
+//	01243000->65299970 peso=0.5kg dim=16x11x5cm formato=1 servicos=04014,04510 vd=0
+//
+// Formatos, AvisoRecebimento, MaoPropria, CdEmpresa e Mode só são
+// incluídos quando informados.
+func (r *FreteRequest) String() string {
+	if r == nil {
+		return "<nil>"
+	}
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%s->%s peso=%skg", r.CepOrigem, r.CepDestino, r.PesoKg)
+	if r.Formato == FormatoRoloCilindro {
+		fmt.Fprintf(b, " dim=%sx%scm", r.ComprimentoCm, r.DiametroCm)
+	} else {
+		fmt.Fprintf(b, " dim=%sx%sx%scm", r.ComprimentoCm, r.LarguraCm, r.AlturaCm)
+	}
+	svcs := make([]string, len(r.Servicos))
+	for k, v := range r.Servicos {
+		svcs[k] = string(v)
+	}
+	fmt.Fprintf(b, " formato=%d servicos=%s vd=%s", int(r.Formato), strings.Join(svcs, ","), r.ValorDeclarado)
+	if len(r.Formatos) > 0 {
+		formatos := make([]string, 0, len(r.Formatos))
+		for k, v := range r.Formatos {
+			formatos = append(formatos, fmt.Sprintf("%s:%d", string(k), int(v)))
+		}
+		sort.Strings(formatos)
+		b.WriteString(" formatos=" + strings.Join(formatos, ","))
+	}
+	if r.AvisoRecebimento {
+		b.WriteString(" ar")
+	}
+	if r.MaoPropria {
+		b.WriteString(" mp")
+	}
+	if r.CdEmpresa != "" {
+		b.WriteString(" cdEmpresa=" + r.CdEmpresa)
+	}
+	if r.DsSenha != "" {
+		b.WriteString(" dsSenha=***")
+	}
+	if r.Mode != RequestModeAuto {
+		fmt.Fprintf(b, " mode=%d", int(r.Mode))
+	}
+	return b.String()
 }
 
 // SetServicos troca os tipos de serviço a serem consultados
@@ -187,11 +307,119 @@ func (r *FreteRequest) AppendServico(srv TipoServico) *FreteRequest {
 	return r
 }
 
+// SetPesoKg altera o peso (kg)
+func (r *FreteRequest) SetPesoKg(peso float64) *FreteRequest {
+	r.PesoKg = decimal.NewFromFloat(peso)
+	return r
+}
+
+// PesoMinimoKg é o peso mínimo considerado pelos Correios (kg); pesos
+// menores são cobrados como este valor
+var PesoMinimoKg = decimal.NewFromFloat(0.3)
+
+// SetPesoGramas altera o peso a partir de um valor em gramas, evitando
+// confusões de unidade. Pesos abaixo de PesoMinimoKg são elevados ao
+// mínimo. O peso enviado continua sendo PesoKg.
+func (r *FreteRequest) SetPesoGramas(gramas int) *FreteRequest {
+	r.PesoKg = decimal.NewFromInt(int64(gramas)).Div(decimal.NewFromInt(1000))
+	if r.PesoKg.LessThan(PesoMinimoKg) {
+		r.PesoKg = PesoMinimoKg
+	}
+	return r
+}
+
+// PesoGramas retorna PesoKg em gramas (arredondado)
+func (r *FreteRequest) PesoGramas() int {
+	return int(r.PesoKg.Mul(decimal.NewFromInt(1000)).Round(0).IntPart())
+}
+
+// FatorCubagem é o fator utilizado pelos Correios no cálculo do peso cúbico
+const FatorCubagem = 6000
+
+// PesoCubico retorna o peso cúbico (volumétrico) em kg:
+// comprimento × largura × altura (cm) / fator, arredondado em 3 casas. Se
+// fator <= 0, FatorCubagem é utilizado.
+func PesoCubico(comprimento, largura, altura decimal.Decimal, fator int) decimal.Decimal {
+	if fator <= 0 {
+		fator = FatorCubagem
+	}
+	return comprimento.Mul(largura).Mul(altura).Div(decimal.NewFromInt(int64(fator))).Round(3)
+}
+
+// PesoCobravel retorna o maior valor entre PesoKg e o peso cúbico do objeto
+// (ver PesoCubico), que é o peso normalmente utilizado pelos Correios na
+// cobrança. Rolos utilizam comprimento × diâmetro × diâmetro; envelopes não
+// possuem peso cúbico.
+func (r *FreteRequest) PesoCobravel() decimal.Decimal {
+	var cubico decimal.Decimal
+	switch r.Formato {
+	case FormatoEnvelope:
+		return r.PesoKg
+	case FormatoRoloCilindro:
+		cubico = PesoCubico(r.ComprimentoCm, r.DiametroCm, r.DiametroCm, FatorCubagem)
+	default:
+		cubico = PesoCubico(r.ComprimentoCm, r.LarguraCm, r.AlturaCm, FatorCubagem)
+	}
+	return decimal.Max(r.PesoKg, cubico)
+}
+
+// SetDimensoesCm altera o comprimento, a largura e a altura (cm)
+func (r *FreteRequest) SetDimensoesCm(comprimento, largura, altura float64) *FreteRequest {
+	r.ComprimentoCm = decimal.NewFromFloat(comprimento)
+	r.LarguraCm = decimal.NewFromFloat(largura)
+	r.AlturaCm = decimal.NewFromFloat(altura)
+	return r
+}
+
+// SetValorDeclarado altera o valor declarado (R$)
+func (r *FreteRequest) SetValorDeclarado(valor decimal.Decimal) *FreteRequest {
+	r.ValorDeclarado = valor
+	return r
+}
+
+// SetValorDeclaradoFloat altera o valor declarado (R$)
+func (r *FreteRequest) SetValorDeclaradoFloat(valor float64) *FreteRequest {
+	r.ValorDeclarado = decimal.NewFromFloat(valor)
+	return r
+}
+
+// SetValorDeclaradoCentavos altera o valor declarado (em centavos, ex.:
+// 15090 = R$ 150,90)
+func (r *FreteRequest) SetValorDeclaradoCentavos(centavos int64) *FreteRequest {
+	r.ValorDeclarado = ReaisFromCentavos(centavos)
+	return r
+}
+
+// ReaisFromCentavos converte um valor em centavos p/ reais
+func ReaisFromCentavos(centavos int64) decimal.Decimal {
+	return decimal.New(centavos, -2)
+}
+
 // FreteResponse resposta dos correios
 type FreteResponse struct {
-	Servicos map[TipoServico]ServicoResponse
+	Servicos map[TipoServico]ServicoResponse `json:"servicos"`
+	// Timestamp é o momento em que a resposta foi obtida
+	Timestamp time.Time `json:"timestamp"`
+	// Origem indica de onde a resposta foi obtida
+	Origem Origem `json:"origem"`
+	// AjusteValorDeclarado é preenchido quando o valor declarado do request
+	// foi ajustado antes do envio (ver Client.ClampValorDeclarado)
+	AjusteValorDeclarado *AjusteValorDeclarado `json:"ajusteValorDeclarado,omitempty"`
 }
 
+// Origem indica de onde um FreteResponse foi obtido
+type Origem string
+
+// Origens de um FreteResponse
+const (
+	// OrigemCorreios a resposta foi obtida dos Correios
+	OrigemCorreios Origem = "correios"
+	// OrigemFallback a resposta foi obtida da FallbackFunc
+	OrigemFallback Origem = "fallback"
+	// OrigemCache a resposta foi obtida de um cache
+	OrigemCache Origem = "cache"
+)
+
 // Any retorna o primeiro serviço recebido
 func (r *FreteResponse) Any() ServicoResponse {
 	if r.Servicos == nil || len(r.Servicos) == 0 {
@@ -209,24 +437,250 @@ func (r *FreteResponse) Any() ServicoResponse {
 	}
 }
 
+// Err retorna um *ServicosError caso nenhum serviço tenha sido precificado
+// com sucesso, ou nil caso ao menos um serviço não possua erro
+func (r *FreteResponse) Err() error {
+	e := &ServicosError{
+		Erros: make(map[TipoServico]*ServicoResponseError),
+	}
+	for k, v := range r.Servicos {
+		if v.Erro == nil {
+			return nil
+		}
+		e.Erros[k] = v.Erro
+	}
+	return e
+}
+
+// Cheapest retorna o serviço (sem Erro ou ParseErr) com o menor preço. ok é false caso
+// nenhum serviço tenha sido precificado com sucesso.
+func (r *FreteResponse) Cheapest() (svc ServicoResponse, ok bool) {
+	for _, v := range r.Servicos {
+		if v.Erro != nil || v.ParseErr != nil {
+			continue
+		}
+		if !ok || v.Preco.LessThan(svc.Preco) ||
+			(v.Preco.Equal(svc.Preco) && v.Tipo < svc.Tipo) {
+			svc = v
+			ok = true
+		}
+	}
+	return
+}
+
+// Fastest retorna o serviço (sem Erro ou ParseErr) com o menor prazo de entrega. Em caso
+// de empate, o serviço mais barato é escolhido. ok é false caso nenhum
+// serviço tenha sido precificado com sucesso.
+func (r *FreteResponse) Fastest() (svc ServicoResponse, ok bool) {
+	for _, v := range r.Servicos {
+		if v.Erro != nil || v.ParseErr != nil {
+			continue
+		}
+		if !ok || v.PrazoEntregaDias < svc.PrazoEntregaDias ||
+			(v.PrazoEntregaDias == svc.PrazoEntregaDias && v.Preco.LessThan(svc.Preco)) ||
+			(v.PrazoEntregaDias == svc.PrazoEntregaDias && v.Preco.Equal(svc.Preco) && v.Tipo < svc.Tipo) {
+			svc = v
+			ok = true
+		}
+	}
+	return
+}
+
+// MergeFreteResponses une os serviços das respostas (ex.: consultas
+// separadas por grupo de serviços ou por origem). Respostas nil são
+// ignoradas. Quando um serviço aparece em mais de uma resposta, prevalece:
+//
+// 1. o serviço sem erro (Erro ou ParseErr);
+// 2. o mais barato;
+// 3. o de menor prazo;
+// 4. o da primeira resposta.
+//
+// Timestamp é o mais antigo entre as respostas e Origem é mantida somente
+// se for a mesma em todas.
+func MergeFreteResponses(resps ...*FreteResponse) *FreteResponse {
+	out := &FreteResponse{
+		Servicos: make(map[TipoServico]ServicoResponse),
+	}
+	first := true
+	for _, r := range resps {
+		if r == nil {
+			continue
+		}
+		if first {
+			out.Origem = r.Origem
+			first = false
+		} else if out.Origem != r.Origem {
+			out.Origem = ""
+		}
+		if !r.Timestamp.IsZero() && (out.Timestamp.IsZero() || r.Timestamp.Before(out.Timestamp)) {
+			out.Timestamp = r.Timestamp
+		}
+		for k, v := range r.Servicos {
+			prev, ok := out.Servicos[k]
+			if !ok || mergePreferir(v, prev) {
+				out.Servicos[k] = v
+			}
+		}
+	}
+	return out
+}
+
+// mergePreferir informa se a deve substituir b em MergeFreteResponses
+func mergePreferir(a, b ServicoResponse) bool {
+	aok := a.Erro == nil && a.ParseErr == nil
+	bok := b.Erro == nil && b.ParseErr == nil
+	if aok != bok {
+		return aok
+	}
+	if !aok {
+		return false
+	}
+	if !a.Preco.Equal(b.Preco) {
+		return a.Preco.LessThan(b.Preco)
+	}
+	return a.PrazoEntregaDias < b.PrazoEntregaDias
+}
+
+// Lista retorna os serviços como um slice ordenado pelo código do serviço,
+// incluindo os serviços com erro na mesma ordem (útil em templates)
+func (r *FreteResponse) Lista() []ServicoResponse {
+	list := make([]ServicoResponse, 0, len(r.Servicos))
+	for _, v := range r.Servicos {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Tipo < list[j].Tipo
+	})
+	return list
+}
+
+// Sorted retorna os serviços ordenados pelo código do serviço. Serviços com
+// erro são posicionados no final.
+func (r *FreteResponse) Sorted() []ServicoResponse {
+	return r.sorted(func(a, b ServicoResponse) bool {
+		return false
+	})
+}
+
+// SortedByPreco retorna os serviços ordenados pelo preço (menor primeiro).
+// Serviços com erro são posicionados no final.
+func (r *FreteResponse) SortedByPreco() []ServicoResponse {
+	return r.sorted(func(a, b ServicoResponse) bool {
+		return a.Preco.LessThan(b.Preco)
+	})
+}
+
+// SortedByPrazo retorna os serviços ordenados pelo prazo de entrega (menor
+// primeiro). Serviços com erro são posicionados no final.
+func (r *FreteResponse) SortedByPrazo() []ServicoResponse {
+	return r.sorted(func(a, b ServicoResponse) bool {
+		return a.PrazoEntregaDias < b.PrazoEntregaDias
+	})
+}
+
+// sorted ordena os serviços utilizando less; o código do serviço é
+// utilizado como critério de desempate.
+func (r *FreteResponse) sorted(less func(a, b ServicoResponse) bool) []ServicoResponse {
+	list := make([]ServicoResponse, 0, len(r.Servicos))
+	for _, v := range r.Servicos {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if (a.Erro == nil) != (b.Erro == nil) {
+			return a.Erro == nil
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return a.Tipo < b.Tipo
+	})
+	return list
+}
+
 // ServicoResponseError é a resposta de erro da API dos Correios
 type ServicoResponseError struct {
-	Codigo TipoErro
+	Codigo TipoErro `json:"codigo"`
 }
 
 // ServicoResponse representa os dados retornados para um tipo de serviço
 type ServicoResponse struct {
-	Tipo                  TipoServico
-	Preco                 decimal.Decimal // preço != valor != custo; deveria ser tudo preço
-	PrazoEntregaDias      int
-	PrecoSemAdicionais    decimal.Decimal
-	PrecoMaoPropria       decimal.Decimal
-	PrecoAvisoRecebimento decimal.Decimal
-	PrecoValorDeclarado   decimal.Decimal
-	EntregaDomiciliar     bool
-	EntregaSabado         bool
-	Erro                  *ServicoResponseError
-	ErroMsg               string
+	Tipo                  TipoServico           `json:"codigo"`
+	Preco                 decimal.Decimal       `json:"preco"` // preço != valor != custo; deveria ser tudo preço
+	PrazoEntregaDias      int                   `json:"prazoEntregaDias"`
+	PrecoSemAdicionais    decimal.Decimal       `json:"precoSemAdicionais"`
+	PrecoMaoPropria       decimal.Decimal       `json:"precoMaoPropria"`
+	PrecoAvisoRecebimento decimal.Decimal       `json:"precoAvisoRecebimento"`
+	PrecoValorDeclarado   decimal.Decimal       `json:"precoValorDeclarado"`
+	EntregaDomiciliar     bool                  `json:"entregaDomiciliar"`
+	EntregaSabado         bool                  `json:"entregaSabado"`
+	EntregaDomiciliarRaw  string                `json:"entregaDomiciliarRaw,omitempty"` // valor original ("S"/"N"), para auditoria
+	EntregaSabadoRaw      string                `json:"entregaSabadoRaw,omitempty"`     // valor original ("S"/"N"), para auditoria
+	DataMaxEntrega        string                `json:"dataMaxEntrega,omitempty"`       // data máxima de entrega informada pelos Correios (dd/mm/aaaa, quando presente)
+	Observacoes           string                `json:"observacoes,omitempty"`          // observações (obsFim)
+	Erro                  *ServicoResponseError `json:"erro,omitempty"`
+	ErroMsg               string                `json:"erroMsg,omitempty"`
+	// ParseErr é preenchido caso algum dos preços retornados pelos Correios
+	// não possa ser interpretado (o preço correspondente fica zerado)
+	ParseErr error `json:"-"`
+}
+
+// EntregaInfo descreve as condições de entrega do serviço, por exemplo
+// "entrega domiciliar, entrega aos sábados"
+func (s ServicoResponse) EntregaInfo() string {
+	info := make([]string, 0, 2)
+	if s.EntregaDomiciliar {
+		info = append(info, "entrega domiciliar")
+	} else {
+		info = append(info, "sem entrega domiciliar")
+	}
+	if s.EntregaSabado {
+		info = append(info, "entrega aos sábados")
+	} else {
+		info = append(info, "sem entrega aos sábados")
+	}
+	return strings.Join(info, ", ")
+}
+
+// PrazoEntrega retorna PrazoEntregaDias como time.Duration (dias de 24h).
+// Note que o prazo dos Correios é contado em dias úteis; ver
+// DataEntregaEstimada.
+func (s ServicoResponse) PrazoEntrega() time.Duration {
+	return time.Duration(s.PrazoEntregaDias) * 24 * time.Hour
+}
+
+// DataEntregaEstimada retorna a data estimada de entrega a partir de from,
+// contando PrazoEntregaDias dias úteis. Domingos nunca são contados e
+// sábados são contados somente se EntregaSabado for true. Os feriados são
+// obtidos de Feriado (ver DataEntregaEstimadaFeriados).
+func (s ServicoResponse) DataEntregaEstimada(from time.Time) time.Time {
+	return s.DataEntregaEstimadaFeriados(from, Feriado)
+}
+
+// DataEntregaEstimadaFeriados funciona como DataEntregaEstimada, ignorando
+// também os dias em que feriado retornar true (ex.: feriados regionais do
+// destino). feriado pode ser nil.
+func (s ServicoResponse) DataEntregaEstimadaFeriados(from time.Time, feriado FeriadoFunc) time.Time {
+	d := from
+	for n := s.PrazoEntregaDias; n > 0; {
+		d = d.AddDate(0, 0, 1)
+		if feriado != nil && feriado(d) {
+			continue
+		}
+		switch d.Weekday() {
+		case time.Sunday:
+			continue
+		case time.Saturday:
+			if !s.EntregaSabado {
+				continue
+			}
+		}
+		n--
+	}
+	return d
 }
 
 // xml wrapper for ServicoResponse
@@ -242,16 +696,28 @@ type servicoResp struct {
 	EntregaSabado         string
 	Erro                  int
 	MsgErro               string
+	DataMaxEntrega        string
+	ObsFim                string `xml:"obsFim"`
 }
 
+// DefaultServicos são os serviços utilizados por NewFreteRequest; se vazio,
+// []{SvcSEDEXVarejo, SvcPACVarejo}
+var DefaultServicos []TipoServico
+
 // NewFreteRequest cria um struct *FreteRequest com os defaults:
 //
 // PesoKg         0.5
 // ComprimentoCm 16.0
 // LarguraCm     11.0
 // AlturaCm       5.0
-// Servicos      []{SvcSEDEXVarejo, SvcPACVarejo}
+// Formato       FormatoCaixaPacote
+// Servicos      DefaultServicos ou []{SvcSEDEXVarejo, SvcPACVarejo}
 func NewFreteRequest(cepOrigem, cepDestino string) *FreteRequest {
+	servicos := []TipoServico{SvcSEDEXVarejo, SvcPACVarejo}
+	if len(DefaultServicos) > 0 {
+		servicos = make([]TipoServico, len(DefaultServicos))
+		copy(servicos, DefaultServicos)
+	}
 	return &FreteRequest{
 		CepOrigem:      cepOrigem,
 		CepDestino:     cepDestino,
@@ -259,7 +725,8 @@ func NewFreteRequest(cepOrigem, cepDestino string) *FreteRequest {
 		ComprimentoCm:  decimal.NewFromFloat(16.0),
 		LarguraCm:      decimal.NewFromFloat(11.0),
 		AlturaCm:       decimal.NewFromFloat(5.0),
-		Servicos:       []TipoServico{SvcSEDEXVarejo, SvcPACVarejo},
+		Formato:        FormatoCaixaPacote,
+		Servicos:       servicos,
 		ValorDeclarado: decimal.NewFromFloat(0.0),
 	}
 }
@@ -268,81 +735,350 @@ func NewFreteRequest(cepOrigem, cepDestino string) *FreteRequest {
 // um *FreteRequest
 // http://ws.correios.com.br/calculador/CalcPrecoPrazo.aspx?sCepOrigem=01243000&sCepDestino=04041002&nVlPeso=1&nCdFormato=1&nVlComprimento=16&nVlAltura=5&nVlLargura=11&StrRetorno=xml&nCdServico=40010,41106&nVlValorDeclarado=0
 func CalcularFrete(ctx context.Context, req *FreteRequest) (*FreteResponse, error) {
+	return DefaultClient.CalcularFrete(ctx, req)
+}
+
+// CalcularFreteSimples funciona como CalcularFrete utilizando
+// context.Background() (útil em scripts e ferramentas de linha de comando).
+// GlobalTimeout, se > 0, continua sendo aplicado.
+func CalcularFreteSimples(req *FreteRequest) (*FreteResponse, error) {
+	return CalcularFrete(context.Background(), req)
+}
+
+// CalcularFreteWith funciona como CalcularFrete, porém utiliza o
+// *http.Client informado (útil p/ configurar timeouts, proxies ou um
+// transport compartilhado). Se client for nil, http.DefaultClient é utilizado.
+func CalcularFreteWith(ctx context.Context, client *http.Client, req *FreteRequest) (*FreteResponse, error) {
+	return (&Client{HTTPClient: client}).CalcularFrete(ctx, req)
+}
+
+// CalcularFrete envia o request p/ calcular o frete utilizando as
+// configurações do Client
+func (c *Client) CalcularFrete(ctx context.Context, req *FreteRequest) (*FreteResponse, error) {
 	if req == nil {
 		return nil, errors.New("nil request")
 	}
+	var ajuste *AjusteValorDeclarado
+	if c.ClampValorDeclarado {
+		req = req.Clone()
+		ajuste = req.ClampValorDeclarado()
+	}
+	var chave string
+	if c.FreteCache != nil {
+		chave = c.freteCacheKey(req)
+		if !req.IgnorarCache {
+			if output, ok := c.FreteCache.Get(chave); ok {
+				output.Origem = OrigemCache
+				// o ajuste é o do request atual (a chave utiliza o valor
+				// já ajustado)
+				output.AjusteValorDeclarado = ajuste
+				c.medirFrete(output, nil)
+				return output, nil
+			}
+		}
+	}
+	output, err := c.calcularFrete(ctx, req)
+	c.medirFrete(output, err)
+	if c.FreteCache != nil && err == nil && cacheableFrete(output) {
+		c.FreteCache.Set(chave, output.clone())
+	}
+	if output != nil && ajuste != nil {
+		output.AjusteValorDeclarado = ajuste
+	}
+	return output, err
+}
+
+func (c *Client) calcularFrete(ctx context.Context, req *FreteRequest) (*FreteResponse, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Mode == RequestModeCombined && req.formatosMistos() {
+		return nil, ErrFormatosMistos
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	if req.CdEmpresa == "" && c.CdEmpresa != "" {
+		req = req.Clone()
+		req.CdEmpresa = c.CdEmpresa
+		req.DsSenha = c.DsSenha
+	}
+	if c.CWS != nil && len(req.Servicos) > 0 {
+		output, err := retryFrete(ctx, c.retry(), func() (*FreteResponse, error) {
+			return c.calcularFreteCWS(ctx, req)
+		})
+		if err == nil || c.CWS.SemFallback {
+			return output, err
+		}
+	}
+	return c.calcularFreteLegado(ctx, req)
+}
+
+// calcularFreteLegado calcula o frete utilizando o FreteEndpoint (ou o
+// FreteSOAPEndpoint). Os requests separados por serviço também são enviados
+// por aqui, sem uma nova tentativa na API REST.
+func (c *Client) calcularFreteLegado(ctx context.Context, req *FreteRequest) (*FreteResponse, error) {
 	// desde 2019, os Correios não aceitam consultas múltiplas caso não seja
 	// informado o código da empresa + senha
+	// serviços com formatos diferentes (Formatos) também são consultados
+	// separadamente
 	if len(req.Servicos) > 1 &&
-		((req.Mode == RequestModeAuto && req.CdEmpresa == "") || (req.Mode == RequestModeSingle)) {
+		((req.Mode == RequestModeAuto && (req.CdEmpresa == "" || req.formatosMistos())) || (req.Mode == RequestModeSingle)) {
 		reqs := make([]*FreteRequest, len(req.Servicos))
 		for k := range req.Servicos {
-			clone := &FreteRequest{
-				CepOrigem:        FilterCEP(req.CepOrigem),
-				CepDestino:       FilterCEP(req.CepDestino),
-				PesoKg:           req.PesoKg,
-				ComprimentoCm:    req.ComprimentoCm,
-				AlturaCm:         req.AlturaCm,
-				LarguraCm:        req.LarguraCm,
-				Servicos:         []TipoServico{req.Servicos[k]},
-				ValorDeclarado:   req.ValorDeclarado,
-				AvisoRecebimento: req.AvisoRecebimento,
-				CdEmpresa:        req.CdEmpresa,
-				DsSenha:          req.DsSenha,
-			}
+			clone := req.Clone()
+			clone.CepOrigem = FilterCEP(req.CepOrigem)
+			clone.CepDestino = FilterCEP(req.CepDestino)
+			clone.Servicos = []TipoServico{req.Servicos[k]}
+			clone.Formato = req.FormatoServico(req.Servicos[k])
+			clone.Formatos = nil
 			reqs[k] = clone
 		}
 		r00 := &FreteResponse{
-			Servicos: make(map[TipoServico]ServicoResponse),
+			Servicos:  make(map[TipoServico]ServicoResponse),
+			Timestamp: time.Now(),
+			Origem:    OrigemCorreios,
 		}
+		// os requests são enviados em paralelo
+		rsps := make([]*FreteResponse, len(reqs))
+		errs := make([]error, len(reqs))
+		wg := sync.WaitGroup{}
 		for i, v := range reqs {
-			rsp, err := CalcularFrete(ctx, v)
-			if err != nil && len(reqs) == i+1 {
-				return r00, err
-			} else if err != nil {
+			wg.Add(1)
+			go func(i int, v *FreteRequest) {
+				defer wg.Done()
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					return
+				}
+				rsps[i], errs[i] = c.calcularFreteLegado(ctx, v)
+			}(i, v)
+		}
+		wg.Wait()
+		// os serviços com falha (ex.: erro de rede) são mantidos na
+		// resposta com ErrIndeterminado; o erro só é retornado caso todos
+		// os requests falhem
+		nerrs := 0
+		interrompidos := make(map[TipoServico]*ServicoResponseError)
+		for i, rsp := range rsps {
+			if errs[i] != nil {
+				nerrs++
+				svc := reqs[i].Servicos[0]
+				r00.Servicos[svc] = ServicoResponse{
+					Tipo:    svc,
+					Erro:    &ServicoResponseError{Codigo: ErrIndeterminado},
+					ErroMsg: errs[i].Error(),
+				}
+				if isContextErr(errs[i]) {
+					interrompidos[svc] = r00.Servicos[svc].Erro
+				}
 				continue
 			}
 			for k2, v2 := range rsp.Servicos {
 				r00.Servicos[k2] = v2
 			}
 		}
+		if err := ctx.Err(); err != nil {
+			// os serviços já consultados são retornados junto com o erro
+			if nerrs == len(reqs) {
+				return nil, err
+			}
+			return r00, &ServicosError{Erros: interrompidos, Err: err}
+		}
+		if nerrs == len(reqs) {
+			return r00, errs[0]
+		}
 		return r00, nil
 	}
+	v := req.Values()
+
+	fallback := c.fallbackFunc()
+	if fallback != nil && c.alwaysUseFallback() {
+		return callFallback(ctx, fallback, v)
+	}
+	cb := c.CircuitBreaker
+	if cb != nil && !cb.permitir() {
+		if fallback != nil {
+			return callFallback(ctx, fallback, v)
+		}
+		return nil, ErrCircuitoAberto
+	}
+	endpoint := c.freteEndpoint()
+	if c.FreteSOAP {
+		endpoint = c.freteSOAPEndpoint()
+	}
+	output, err := c.freteCompartilhado(ctx, endpoint+"?"+v.Encode(), func(ctx context.Context) (*FreteResponse, error) {
+		output, err := retryFrete(ctx, c.retry(), func() (*FreteResponse, error) {
+			return c.requestFrete(ctx, v)
+		})
+		// o resultado é registrado uma única vez por request compartilhado
+		if cb != nil {
+			cb.registrar(resultadoRequest(ctx, err))
+		}
+		return output, err
+	})
+	if err != nil && fallback != nil && ctx.Err() == nil {
+		return callFallback(ctx, fallback, v)
+	}
+	return output, err
+}
+
+// callFallback chama fallback, marcando a resposta com OrigemFallback
+func callFallback(ctx context.Context, fallback func(ctx context.Context, v url.Values) (*FreteResponse, error), v url.Values) (*FreteResponse, error) {
+	output, err := fallback(ctx, v)
+	if output != nil {
+		output.Origem = OrigemFallback
+		if output.Timestamp.IsZero() {
+			output.Timestamp = time.Now()
+		}
+	}
+	return output, err
+}
+
+// Values retorna os parâmetros do request ao FreteEndpoint (nomes dos
+// Correios, ex.: sCepOrigem, nVlPeso). Ver FreteRequestFromValues.
+func (r *FreteRequest) Values() url.Values {
 	v := url.Values{}
-	v.Set("sCepOrigem", strings.Trim(req.CepOrigem, "-"))
-	v.Set("sCepDestino", strings.Trim(req.CepDestino, "-"))
-	v.Set("nVlPeso", req.PesoKg.String())
-	v.Set("nCdFormato", "1")
-	v.Set("nVlComprimento", req.ComprimentoCm.String())
-	v.Set("nVlAltura", req.AlturaCm.String())
-	v.Set("nVlLargura", req.LarguraCm.String())
+	v.Set("sCepOrigem", strings.Trim(r.CepOrigem, "-"))
+	v.Set("sCepDestino", strings.Trim(r.CepDestino, "-"))
+	v.Set("nVlPeso", r.PesoKg.String())
+	formato := r.formatoEfetivo()
+	if formato != 0 {
+		v.Set("nCdFormato", strconv.Itoa(int(formato)))
+	} else {
+		v.Set("nCdFormato", strconv.Itoa(int(FormatoCaixaPacote)))
+	}
+	v.Set("nVlComprimento", r.ComprimentoCm.String())
+	if formato == FormatoRoloCilindro {
+		// altura e largura são exclusivas de caixa/pacote
+		v.Set("nVlAltura", "0")
+		v.Set("nVlLargura", "0")
+		v.Set("nVlDiametro", r.DiametroCm.String())
+	} else {
+		v.Set("nVlAltura", r.AlturaCm.String())
+		v.Set("nVlLargura", r.LarguraCm.String())
+	}
 	v.Set("StrRetorno", "xml")
-	svcs := make([]string, len(req.Servicos))
-	for k, v := range req.Servicos {
+	svcs := make([]string, len(r.Servicos))
+	for k, v := range r.Servicos {
 		svcs[k] = string(v)
 	}
 	v.Set("nCdServico", strings.Join(svcs, ","))
-	v.Set("nVlValorDeclarado", req.ValorDeclarado.String())
-	if req.AvisoRecebimento {
+	v.Set("nVlValorDeclarado", r.ValorDeclarado.String())
+	if r.AvisoRecebimento {
 		v.Set("sCdAvisoRecebimento", "S")
 	}
-	if req.CdEmpresa != "" {
-		v.Set("nCdEmpresa", req.CdEmpresa)
-		v.Set("sDsSenha", req.DsSenha)
+	if r.MaoPropria {
+		v.Set("sCdMaoPropria", "S")
+	}
+	if r.CdEmpresa != "" {
+		v.Set("nCdEmpresa", r.CdEmpresa)
+		v.Set("sDsSenha", r.DsSenha)
+	}
+	return v
+}
+
+// FreteRequestFromValues cria um *FreteRequest a partir dos parâmetros do
+// FreteEndpoint (ver Values), útil p/ formulários HTTP e FallbackFunc.
+// sCepOrigem e sCepDestino são obrigatórios; os demais parâmetros, se
+// ausentes, utilizam os defaults de NewFreteRequest. Números aceitam o
+// formato brasileiro ("1,5"). Parâmetros que não puderem ser interpretados
+// resultam em um *CampoInvalidoError; o request é então validado com
+// Validate (avisos são ignorados).
+func FreteRequestFromValues(v url.Values) (*FreteRequest, error) {
+	return DefaultClient.FreteRequestFromValues(v)
+}
+
+// FreteRequestFromValues cria um *FreteRequest a partir dos parâmetros do
+// FreteEndpoint (ver a função FreteRequestFromValues), validado com
+// ValidarFrete: os serviços com contrato são aceitos sem nCdEmpresa caso o
+// Client possua credenciais (ver WithCredentials).
+func (c *Client) FreteRequestFromValues(v url.Values) (*FreteRequest, error) {
+	r := NewFreteRequest(v.Get("sCepOrigem"), v.Get("sCepDestino"))
+	for _, campo := range []struct {
+		nome string
+		dst  *decimal.Decimal
+	}{
+		{"nVlPeso", &r.PesoKg},
+		{"nVlComprimento", &r.ComprimentoCm},
+		{"nVlAltura", &r.AlturaCm},
+		{"nVlLargura", &r.LarguraCm},
+		{"nVlDiametro", &r.DiametroCm},
+		{"nVlValorDeclarado", &r.ValorDeclarado},
+	} {
+		s := strings.TrimSpace(v.Get(campo.nome))
+		if s == "" {
+			continue
+		}
+		d, err := parseDecimal(s)
+		if err != nil {
+			return nil, &CampoInvalidoError{Campo: campo.nome, Valor: s, Err: err}
+		}
+		if d.IsNegative() {
+			return nil, &CampoInvalidoError{Campo: campo.nome, Valor: s, Err: errors.New("valor negativo")}
+		}
+		*campo.dst = d
+	}
+	if s := strings.TrimSpace(v.Get("nCdFormato")); s != "" {
+		f, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, &CampoInvalidoError{Campo: "nCdFormato", Valor: s, Err: err}
+		}
+		switch Formato(f) {
+		case FormatoCaixaPacote, FormatoRoloCilindro, FormatoEnvelope:
+			r.Formato = Formato(f)
+		default:
+			return nil, &CampoInvalidoError{Campo: "nCdFormato", Valor: s, Err: errors.New("formato desconhecido")}
+		}
+	}
+	if s := strings.TrimSpace(v.Get("nCdServico")); s != "" {
+		r.Servicos = r.Servicos[:0]
+		for _, svc := range strings.Split(s, ",") {
+			if svc = strings.TrimSpace(svc); svc != "" {
+				r.AppendServico(TipoServico(svc))
+			}
+		}
+	}
+	for _, campo := range []struct {
+		nome string
+		dst  *bool
+	}{
+		{"sCdMaoPropria", &r.MaoPropria},
+		{"sCdAvisoRecebimento", &r.AvisoRecebimento},
+	} {
+		switch s := strings.ToUpper(strings.TrimSpace(v.Get(campo.nome))); s {
+		case "", "N":
+		case "S":
+			*campo.dst = true
+		default:
+			return nil, &CampoInvalidoError{Campo: campo.nome, Valor: s, Err: errors.New("utilize S ou N")}
+		}
+	}
+	r.CdEmpresa = strings.TrimSpace(v.Get("nCdEmpresa"))
+	r.DsSenha = v.Get("sDsSenha")
+	if err := c.ValidarFrete(r); err != nil {
+		var ve *ValidacaoError
+		if !errors.As(err, &ve) || !ve.Aviso {
+			return nil, err
+		}
 	}
+	return r, nil
+}
 
-	rq0, _ := http.NewRequest(http.MethodGet, FreteEndpoint+"?"+v.Encode(), nil)
+// requestFrete envia os parâmetros v ao FreteEndpoint (ou ao
+// FreteSOAPEndpoint, se FreteSOAP) e decodifica a resposta
+func (c *Client) requestFrete(ctx context.Context, v url.Values) (*FreteResponse, error) {
+	if c.FreteSOAP {
+		return c.requestFreteSOAP(ctx, v)
+	}
+	rq0, _ := http.NewRequest(http.MethodGet, c.freteEndpoint()+"?"+v.Encode(), nil)
 	rq0 = rq0.WithContext(ctx)
+	c.setFreteHeaders(rq0)
 
-	cresp, err := http.DefaultClient.Do(rq0)
+	raw, err := c.lerFrete(rq0, v)
 	if err != nil {
 		return nil, err
 	}
-	defer cresp.Body.Close()
-
-	rrbuf := new(bytes.Buffer)
-	io.Copy(rrbuf, cresp.Body)
-	p := xml.NewDecoder(rrbuf)
+	p := xml.NewDecoder(bytes.NewReader(raw))
 	p.CharsetReader = CharsetReader
 
 	vlov := struct {
@@ -352,33 +1088,81 @@ func CalcularFrete(ctx context.Context, req *FreteRequest) (*FreteResponse, erro
 
 	err = p.Decode(&vlov)
 	if err != nil {
-		fmt.Println("CORREIOS: " + rrbuf.String())
-		return nil, err
+		return nil, &DecodeError{Err: fmt.Errorf("decode xml error: %w (body: %q)", err, snippet(raw, 256))}
 	}
-	//
+	return freteResponse(vlov.Values), nil
+}
+
+// lerFrete envia o request de frete rq e retorna o corpo da resposta
+func (c *Client) lerFrete(rq *http.Request, v url.Values) ([]byte, error) {
+	cresp, err := c.do(rq)
+	if err != nil {
+		return nil, &TransporteError{Err: err}
+	}
+	defer cresp.Body.Close()
+	if cresp.StatusCode != http.StatusOK {
+		return nil, &TransporteError{Err: &httpStatusError{
+			StatusCode: cresp.StatusCode,
+			Status:     cresp.Status,
+		}}
+	}
+
+	rrbuf := new(bytes.Buffer)
+	if _, err := io.Copy(rrbuf, cresp.Body); err != nil {
+		return nil, &TransporteError{Err: err}
+	}
+	raw := rrbuf.Bytes()
+	if c.RawResponseFunc != nil {
+		c.RawResponseFunc(v, raw)
+	}
+	if isHTML(raw) {
+		return nil, &TransporteError{Err: fmt.Errorf("%w (body: %q)", ErrCorreiosIndisponivel, snippet(raw, 256))}
+	}
+	return raw, nil
+}
+
+// freteResponse converte os serviços decodificados em um *FreteResponse
+func freteResponse(values []servicoResp) *FreteResponse {
 	output := &FreteResponse{
-		Servicos: make(map[TipoServico]ServicoResponse),
+		Servicos:  make(map[TipoServico]ServicoResponse),
+		Timestamp: time.Now(),
+		Origem:    OrigemCorreios,
 	}
 	//
-	for _, v := range vlov.Values {
+	for _, v := range values {
 		v2 := ServicoResponse{}
+		parse := func(campo, s string) decimal.Decimal {
+			d, err := parseDecimal(s)
+			if err != nil && v2.ParseErr == nil {
+				v2.ParseErr = fmt.Errorf("%s %q: %w", campo, s, err)
+			}
+			return d
+		}
 		v2.Tipo = TipoServico(v.Codigo)
-		v2.Preco, _ = decimal.NewFromString(fixWrongDecimals(v.Valor))
+		v2.Preco = parse("Valor", v.Valor)
 		v2.PrazoEntregaDias = v.PrazoEntrega
-		v2.PrecoSemAdicionais, _ = decimal.NewFromString(fixWrongDecimals(v.ValorSemAdicionais))
-		v2.PrecoMaoPropria, _ = decimal.NewFromString(fixWrongDecimals(v.ValorMaoPropria))
-		v2.PrecoAvisoRecebimento, _ = decimal.NewFromString(fixWrongDecimals(v.ValorAvisoRecebimento))
-		v2.PrecoValorDeclarado, _ = decimal.NewFromString(fixWrongDecimals(v.ValorValorDeclarado))
+		v2.PrecoSemAdicionais = parse("ValorSemAdicionais", v.ValorSemAdicionais)
+		v2.PrecoMaoPropria = parse("ValorMaoPropria", v.ValorMaoPropria)
+		v2.PrecoAvisoRecebimento = parse("ValorAvisoRecebimento", v.ValorAvisoRecebimento)
+		v2.PrecoValorDeclarado = parse("ValorValorDeclarado", v.ValorValorDeclarado)
 		v2.EntregaDomiciliar = (v.EntregaDomiciliar == "S")
 		v2.EntregaSabado = (v.EntregaSabado == "S")
+		v2.EntregaDomiciliarRaw = v.EntregaDomiciliar
+		v2.EntregaSabadoRaw = v.EntregaSabado
+		v2.DataMaxEntrega = strings.TrimSpace(v.DataMaxEntrega)
+		v2.Observacoes = strings.TrimSpace(v.ObsFim)
 		if v.Erro != 0 {
 			er9 := &ServicoResponseError{
 				Codigo: TipoErro(v.Erro),
 			}
 			v2.Erro = er9
-			v2.ErroMsg = v.MsgErro
+			v2.ErroMsg = strings.TrimSpace(v.MsgErro)
+			if v2.ErroMsg == "" {
+				// os Correios às vezes não enviam a mensagem
+				v2.ErroMsg = er9.Codigo.String()
+			}
 		}
 		output.Servicos[v2.Tipo] = v2
 	}
-	return output, nil
+	return output
 }
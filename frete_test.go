@@ -22,15 +22,744 @@ package correios_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gabstv/correios"
+	"github.com/gabstv/correios/correiostest"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestSimpleRequest(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
 	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
-	resp, err := correios.CalcularFrete(context.Background(), r)
+	resp, err := srv.Client().CalcularFrete(context.Background(), r)
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
+	svc := resp.Servicos[correios.SvcSEDEXVarejo]
+	assert.Nil(t, svc.Erro)
+	assert.Equal(t, "42.5", svc.Preco.String())
+	assert.Equal(t, 2, svc.PrazoEntregaDias)
+	assert.Equal(t, correios.OrigemCorreios, resp.Origem)
+	assert.WithinDuration(t, time.Now(), resp.Timestamp, time.Minute)
+	assert.True(t, svc.EntregaDomiciliar)
+	assert.True(t, svc.EntregaSabado)
+	assert.Equal(t, "S", svc.EntregaDomiciliarRaw)
+	assert.Equal(t, "S", svc.EntregaSabadoRaw)
+	q := srv.Requests()[0]
+	assert.Equal(t, "01243000", q.Get("sCepOrigem"))
+	assert.Equal(t, "65299970", q.Get("sCepDestino"))
+	assert.Equal(t, "0.5", q.Get("nVlPeso"))
+	assert.Equal(t, "1", q.Get("nCdFormato"))
+}
+
+func TestRequestModeSingle(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	srv.SetServico(correiostest.Servico{
+		Codigo:             correios.SvcSEDEXHojeVarejo,
+		Valor:              "1.234,56",
+		ValorSemAdicionais: "1.200,00",
+		PrazoEntrega:       1,
+		EntregaDomiciliar:  true,
+	})
+	srv.SetErro(correios.SvcSEDEX10Varejo, correios.ErrServicoIndisponivelTrecho2)
+	r := correios.NewFreteRequest("01243000", "65299970").
+		SetServicos(correios.SvcPACVarejo, correios.SvcSEDEXHojeVarejo, correios.SvcSEDEX10Varejo)
+	resp, err := srv.Client().CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Len(t, srv.Requests(), 3)
+	assert.Len(t, resp.Servicos, 3)
+	pac := resp.Servicos[correios.SvcPACVarejo]
+	assert.Equal(t, "21.9", pac.Preco.String())
+	assert.False(t, pac.EntregaSabado)
+	hoje := resp.Servicos[correios.SvcSEDEXHojeVarejo]
+	assert.Equal(t, "1234.56", hoje.Preco.String())
+	assert.Equal(t, "1200", hoje.PrecoSemAdicionais.String())
+	assert.NoError(t, hoje.ParseErr)
+	s10 := resp.Servicos[correios.SvcSEDEX10Varejo]
+	assert.Equal(t, correios.ErrServicoIndisponivelTrecho2, s10.Erro.Codigo)
+}
+
+func TestFormatos(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	c.WithCredentials("08082650", "n5f9t8")
+	r := correios.NewFreteRequest("01243000", "65299970")
+	r.Formatos = map[correios.TipoServico]correios.Formato{correios.SvcPACVarejo: correios.FormatoEnvelope}
+	assert.Equal(t, correios.FormatoEnvelope, r.FormatoServico(correios.SvcPACVarejo))
+	assert.Equal(t, correios.FormatoCaixaPacote, r.FormatoServico(correios.SvcSEDEXVarejo))
+	// com contrato, os serviços seriam consultados juntos, mas os formatos
+	// diferentes forçam requests separados
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Servicos, 2)
+	reqs := srv.Requests()
+	if assert.Len(t, reqs, 2) {
+		formatos := map[string]string{}
+		for _, q := range reqs {
+			formatos[q.Get("nCdServico")] = q.Get("nCdFormato")
+		}
+		assert.Equal(t, map[string]string{"04014": "1", "04510": "3"}, formatos)
+	}
+
+	r.Mode = correios.RequestModeCombined
+	_, err = c.CalcularFrete(context.Background(), r)
+	assert.Equal(t, correios.ErrFormatosMistos, err)
+}
+
+func TestPaginaHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("\r\n<!DOCTYPE html><html><body>Sistema em manutenção</body></html>"))
+	}))
+	defer srv.Close()
+	c := &correios.Client{FreteEndpoint: srv.URL}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	_, err := c.CalcularFrete(context.Background(), r)
+	assert.True(t, errors.Is(err, correios.ErrCorreiosIndisponivel))
+	var te *correios.TransporteError
+	assert.True(t, errors.As(err, &te))
+	assert.Contains(t, err.Error(), "Sistema em manutenção")
+}
+
+func TestRequestModeSingleFalhaParcial(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("nCdServico") != string(correios.SvcSEDEXVarejo) {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	c := &correios.Client{FreteEndpoint: srv.URL}
+	r := correios.NewFreteRequest("01243000", "65299970").
+		SetServicos(correios.SvcSEDEXVarejo, correios.SvcPACVarejo)
+	r.Mode = correios.RequestModeSingle
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Len(t, resp.Servicos, 2)
+	assert.Equal(t, "42.5", resp.Servicos[correios.SvcSEDEXVarejo].Preco.String())
+	pac := resp.Servicos[correios.SvcPACVarejo]
+	assert.Equal(t, correios.ErrIndeterminado, pac.Erro.Codigo)
+	assert.Equal(t, "http status: 502 Bad Gateway", pac.ErroMsg)
+
+	// todos os requests falham
+	r.SetServicos(correios.SvcPACVarejo, correios.SvcSEDEX10Varejo)
+	resp, err = c.CalcularFrete(context.Background(), r)
+	assert.Error(t, err)
+	assert.Len(t, resp.Servicos, 2)
+}
+
+func TestDataMaxEntrega(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	sedex := correiostest.SEDEX
+	sedex.DataMaxEntrega = "15/06/2021"
+	sedex.ObsFim = "Entrega sujeita a prazo diferenciado"
+	srv.SetServico(sedex)
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo, correios.SvcPACVarejo)
+	r.Mode = correios.RequestModeSingle
+	resp, err := srv.Client().CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	s := resp.Servicos[correios.SvcSEDEXVarejo]
+	assert.Equal(t, "15/06/2021", s.DataMaxEntrega)
+	assert.Equal(t, "Entrega sujeita a prazo diferenciado", s.Observacoes)
+	assert.Equal(t, "", resp.Servicos[correios.SvcPACVarejo].DataMaxEntrega)
+}
+
+func TestHTTPStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("<html><body>erro</body></html>"))
+	}))
+	defer srv.Close()
+	prev := correios.FreteEndpoint
+	correios.FreteEndpoint = srv.URL
+	defer func() { correios.FreteEndpoint = prev }()
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resp, err := correios.CalcularFrete(context.Background(), r)
+	assert.Nil(t, resp)
+	assert.EqualError(t, err, "http status: 500 Internal Server Error")
+	var te *correios.TransporteError
+	assert.True(t, errors.As(err, &te))
+}
+
+func TestErrorKinds(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<Servicos><cServico><Codigo>04014"))
+	}))
+	c := &correios.Client{FreteEndpoint: srv.URL}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	_, err := c.CalcularFrete(context.Background(), r)
+	var de *correios.DecodeError
+	assert.True(t, errors.As(err, &de))
+	var te *correios.TransporteError
+	assert.False(t, errors.As(err, &te))
+
+	srv.Close()
+	_, err = c.CalcularFrete(context.Background(), r)
+	assert.True(t, errors.As(err, &te))
+	assert.False(t, errors.As(err, &de))
+}
+
+func TestHTTPS(t *testing.T) {
+	assert.True(t, strings.HasPrefix(correios.FreteEndpoint, "https://"))
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0" encoding="ISO-8859-1" ?>
+<Servicos><cServico><Codigo>04014</Codigo><Valor>0,00</Valor><Erro>-3</Erro><MsgErro>CEP de destino inv` + "\xe1" + `lido.</MsgErro></cServico></Servicos>`))
+	}))
+	defer srv.Close()
+	c := &correios.Client{
+		HTTPClient:    srv.Client(),
+		FreteEndpoint: srv.URL,
+	}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, "CEP de destino inválido.", resp.Any().ErroMsg)
+}
+
+func TestFallbackFunc(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	prev := correios.FreteEndpoint
+	correios.FreteEndpoint = srv.URL
+	defer func() { correios.FreteEndpoint = prev }()
+	var fbv url.Values
+	correios.FallbackFunc = func(ctx context.Context, v url.Values) (*correios.FreteResponse, error) {
+		fbv = v
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return &correios.FreteResponse{
+			Servicos: map[correios.TipoServico]correios.ServicoResponse{
+				correios.SvcPACVarejo: {Tipo: correios.SvcPACVarejo, PrazoEntregaDias: 9},
+			},
+		}, nil
+	}
+	defer func() { correios.FallbackFunc = nil }()
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcPACVarejo)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	resp, err := correios.CalcularFrete(ctx, r)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, resp.Any().PrazoEntregaDias)
+	assert.Equal(t, correios.OrigemFallback, resp.Origem)
+	assert.False(t, resp.Timestamp.IsZero())
+	assert.Equal(t, "04510", fbv.Get("nCdServico"))
+	assert.Equal(t, "01243000", fbv.Get("sCepOrigem"))
+}
+
+func testFreteResponse() *correios.FreteResponse {
+	return &correios.FreteResponse{
+		Servicos: map[correios.TipoServico]correios.ServicoResponse{
+			correios.SvcSEDEXVarejo: {
+				Tipo:             correios.SvcSEDEXVarejo,
+				Preco:            decimal.RequireFromString("42.50"),
+				PrazoEntregaDias: 2,
+			},
+			correios.SvcPACVarejo: {
+				Tipo:             correios.SvcPACVarejo,
+				Preco:            decimal.RequireFromString("21.90"),
+				PrazoEntregaDias: 7,
+			},
+			correios.SvcSEDEX10Varejo: {
+				Tipo:    correios.SvcSEDEX10Varejo,
+				Erro:    &correios.ServicoResponseError{Codigo: correios.ErrServicoIndisponivelTrecho},
+				ErroMsg: "Serviço indisponível para o trecho informado",
+			},
+		},
+	}
+}
+
+func TestCheapest(t *testing.T) {
+	svc, ok := testFreteResponse().Cheapest()
+	assert.True(t, ok)
+	assert.Equal(t, correios.SvcPACVarejo, svc.Tipo)
+	_, ok = (&correios.FreteResponse{}).Cheapest()
+	assert.False(t, ok)
+}
+
+func TestFastest(t *testing.T) {
+	resp := testFreteResponse()
+	svc, ok := resp.Fastest()
+	assert.True(t, ok)
+	assert.Equal(t, correios.SvcSEDEXVarejo, svc.Tipo)
+	// empate no prazo: o mais barato vence
+	resp.Servicos[correios.SvcSEDEXHojeVarejo] = correios.ServicoResponse{
+		Tipo:             correios.SvcSEDEXHojeVarejo,
+		Preco:            decimal.RequireFromString("30.00"),
+		PrazoEntregaDias: 2,
+	}
+	svc, ok = resp.Fastest()
+	assert.True(t, ok)
+	assert.Equal(t, correios.SvcSEDEXHojeVarejo, svc.Tipo)
+}
+
+func TestSorted(t *testing.T) {
+	resp := testFreteResponse()
+	tipos := func(list []correios.ServicoResponse) []correios.TipoServico {
+		out := make([]correios.TipoServico, len(list))
+		for k, v := range list {
+			out[k] = v.Tipo
+		}
+		return out
+	}
+	assert.Equal(t, []correios.TipoServico{
+		correios.SvcSEDEXVarejo, correios.SvcPACVarejo, correios.SvcSEDEX10Varejo,
+	}, tipos(resp.Sorted()))
+	assert.Equal(t, []correios.TipoServico{
+		correios.SvcPACVarejo, correios.SvcSEDEXVarejo, correios.SvcSEDEX10Varejo,
+	}, tipos(resp.SortedByPreco()))
+	assert.Equal(t, []correios.TipoServico{
+		correios.SvcSEDEXVarejo, correios.SvcPACVarejo, correios.SvcSEDEX10Varejo,
+	}, tipos(resp.SortedByPrazo()))
+}
+
+func TestLista(t *testing.T) {
+	resp := testFreteResponse()
+	list := resp.Lista()
+	assert.Len(t, list, 3)
+	assert.Equal(t, correios.SvcSEDEXVarejo, list[0].Tipo)
+	assert.Equal(t, correios.SvcPACVarejo, list[1].Tipo)
+	assert.Equal(t, correios.SvcSEDEX10Varejo, list[2].Tipo)
+	assert.Empty(t, (&correios.FreteResponse{}).Lista())
+}
+
+func TestMergeFreteResponses(t *testing.T) {
+	t0 := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	a := testFreteResponse()
+	a.Timestamp = t0.Add(time.Minute)
+	a.Origem = correios.OrigemCorreios
+	b := &correios.FreteResponse{
+		Timestamp: t0,
+		Origem:    correios.OrigemCorreios,
+		Servicos: map[correios.TipoServico]correios.ServicoResponse{
+			// mais caro: ignorado
+			correios.SvcSEDEXVarejo: {Tipo: correios.SvcSEDEXVarejo, Preco: decimal.RequireFromString("50"), PrazoEntregaDias: 1},
+			// mesmo preço, menor prazo
+			correios.SvcPACVarejo: {Tipo: correios.SvcPACVarejo, Preco: decimal.RequireFromString("21.9"), PrazoEntregaDias: 5},
+			// sem erro: substitui o serviço com erro
+			correios.SvcSEDEX10Varejo:   {Tipo: correios.SvcSEDEX10Varejo, Preco: decimal.RequireFromString("80"), PrazoEntregaDias: 1},
+			correios.SvcSEDEXHojeVarejo: {Tipo: correios.SvcSEDEXHojeVarejo, Preco: decimal.RequireFromString("90")},
+		},
+	}
+	m := correios.MergeFreteResponses(a, nil, b)
+	assert.Len(t, m.Servicos, 4)
+	assert.Equal(t, "42.5", m.Servicos[correios.SvcSEDEXVarejo].Preco.String())
+	assert.Equal(t, 5, m.Servicos[correios.SvcPACVarejo].PrazoEntregaDias)
+	assert.Nil(t, m.Servicos[correios.SvcSEDEX10Varejo].Erro)
+	assert.Equal(t, t0, m.Timestamp)
+	assert.Equal(t, correios.OrigemCorreios, m.Origem)
+
+	b.Origem = correios.OrigemFallback
+	assert.Equal(t, correios.Origem(""), correios.MergeFreteResponses(a, b).Origem)
+	assert.Empty(t, correios.MergeFreteResponses().Servicos)
+}
+
+const freteXML = `<?xml version="1.0" encoding="ISO-8859-1" ?>
+<Servicos>
+<cServico>
+<Codigo>04014</Codigo><Valor>42,50</Valor><PrazoEntrega>2</PrazoEntrega><ValorSemAdicionais>42,50</ValorSemAdicionais><ValorMaoPropria>0,00</ValorMaoPropria><ValorAvisoRecebimento>0,00</ValorAvisoRecebimento><ValorValorDeclarado>0,00</ValorValorDeclarado><EntregaDomiciliar>S</EntregaDomiciliar><EntregaSabado>S</EntregaSabado><obsFim></obsFim><Erro>0</Erro><MsgErro></MsgErro>
+</cServico>
+</Servicos>`
+
+func TestRetry(t *testing.T) {
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if n < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	prev := correios.FreteEndpoint
+	correios.FreteEndpoint = srv.URL
+	defer func() { correios.FreteEndpoint = prev }()
+	correios.Retry = &correios.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	defer func() { correios.Retry = nil }()
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resp, err := correios.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, "42.5", resp.Any().Preco.String())
+	// 4xx não é repetido
+	n = -10
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	_, err = correios.CalcularFrete(context.Background(), r)
+	assert.Error(t, err)
+	assert.Equal(t, -9, n)
+}
+
+func TestRetrySemCobertura(t *testing.T) {
+	erro := "007"
+	msg := "Localidade de destino não abrange o serviço informado"
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		w.Write([]byte(`<Servicos><cServico><Codigo>04014</Codigo><Valor>0,00</Valor>` +
+			`<Erro>` + erro + `</Erro><MsgErro>` + msg + `</MsgErro></cServico></Servicos>`))
+	}))
+	defer srv.Close()
+	c := &correios.Client{
+		FreteEndpoint: srv.URL,
+		Retry:         &correios.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.True(t, resp.Any().SemCobertura())
+	assert.Equal(t, 1, n)
+
+	// código 010: o preço é válido
+	n, erro, msg = 0, "010", "CEP de destino está temporariamente sem entrega domiciliar"
+	_, err = c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// código 7 "tente mais tarde" é repetido
+	n, erro, msg = 0, "7", "Serviço indisponível, tente mais tarde"
+	_, err = c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+}
+
+const freteXMLVazio = `<?xml version="1.0" encoding="ISO-8859-1" ?><Servicos></Servicos>`
+
+func TestRetryServicosVazio(t *testing.T) {
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if n < 2 {
+			w.Write([]byte(freteXMLVazio))
+			return
+		}
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	c := &correios.Client{
+		FreteEndpoint: srv.URL,
+		Retry:         &correios.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, "42.5", resp.Any().Preco.String())
+
+	n = 0
+	c.Retry.AceitarVazio = true
+	resp, err = c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, resp.Servicos)
+}
+
+func TestFreteRequestString(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970")
+	assert.Equal(t, "01243000->65299970 peso=0.5kg dim=16x11x5cm formato=1 servicos=04014,04510 vd=0", r.String())
+	r.CdEmpresa, r.DsSenha = "08082650", "n5f9t8"
+	r.MaoPropria = true
+	r.Mode = correios.RequestModeSingle
+	s := fmt.Sprintf("%+v", r)
+	assert.Equal(t, "01243000->65299970 peso=0.5kg dim=16x11x5cm formato=1 servicos=04014,04510 vd=0 mp cdEmpresa=08082650 dsSenha=*** mode=1", s)
+	assert.NotContains(t, s, "n5f9t8")
+	r = correios.NewFreteRequest("01243000", "65299970")
+	r.Formatos = map[correios.TipoServico]correios.Formato{correios.SvcPACVarejo: correios.FormatoEnvelope}
+	assert.Contains(t, r.String(), " formatos=04510:3")
+}
+
+func TestFreteRequestFromValues(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970").SetPesoKg(1.5).SetValorDeclaradoFloat(150)
+	r.SetServicos(correios.SvcSEDEXVarejo, correios.SvcSEDEX10Varejo)
+	r.MaoPropria = true
+	r2, err := correios.FreteRequestFromValues(r.Values())
+	assert.NoError(t, err)
+	assert.Equal(t, r.Values(), r2.Values())
+
+	v := url.Values{}
+	v.Set("sCepOrigem", "01243-000")
+	v.Set("sCepDestino", "65299970")
+	v.Set("nVlPeso", "0,75")
+	v.Set("nCdServico", "04510")
+	r2, err = correios.FreteRequestFromValues(v)
+	assert.NoError(t, err)
+	assert.Equal(t, "0.75", r2.PesoKg.String())
+	assert.Equal(t, []correios.TipoServico{correios.SvcPACVarejo}, r2.Servicos)
+	assert.Equal(t, "16", r2.ComprimentoCm.String())
+
+	v.Set("nVlComprimento", "abc")
+	_, err = correios.FreteRequestFromValues(v)
+	var ce *correios.CampoInvalidoError
+	if assert.True(t, errors.As(err, &ce)) {
+		assert.Equal(t, "nVlComprimento", ce.Campo)
+		assert.Equal(t, "abc", ce.Valor)
+	}
+	v.Set("nVlComprimento", "20")
+	v.Set("sCdMaoPropria", "talvez")
+	_, err = correios.FreteRequestFromValues(v)
+	assert.True(t, errors.As(err, &ce))
+	assert.Equal(t, "sCdMaoPropria", ce.Campo)
+	v.Del("sCdMaoPropria")
+	v.Set("sCepDestino", "123")
+	_, err = correios.FreteRequestFromValues(v)
+	var ve *correios.ValidacaoError
+	assert.True(t, errors.As(err, &ve))
+}
+
+func TestFreteRequestFromValuesContrato(t *testing.T) {
+	v := url.Values{}
+	v.Set("sCepOrigem", "01243000")
+	v.Set("sCepDestino", "65299970")
+	v.Set("nCdServico", string(correios.SvcSEDEXComContrato))
+	_, err := correios.FreteRequestFromValues(v)
+	assert.Equal(t, correios.ErrSemContrato, codigo(err))
+
+	// credenciais do Client
+	c := (&correios.Client{}).WithCredentials("08082650", "n5f9t8")
+	r, err := c.FreteRequestFromValues(v)
+	assert.NoError(t, err)
+	assert.Equal(t, []correios.TipoServico{correios.SvcSEDEXComContrato}, r.Servicos)
+	assert.Empty(t, r.CdEmpresa)
+}
+
+func TestPesoCubico(t *testing.T) {
+	d := decimal.NewFromInt
+	assert.Equal(t, "10", correios.PesoCubico(d(60), d(50), d(20), 0).String())
+	assert.Equal(t, "12", correios.PesoCubico(d(60), d(50), d(20), 5000).String())
+
+	r := correios.NewFreteRequest("01243000", "65299970").SetPesoKg(2)
+	assert.Equal(t, "2", r.PesoCobravel().String())
+	r.SetDimensoesCm(60, 50, 20)
+	assert.Equal(t, "10", r.PesoCobravel().String())
+	r.Formato = correios.FormatoEnvelope
+	assert.Equal(t, "2", r.PesoCobravel().String())
+}
+
+func TestErroComZerosAEsquerda(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0" encoding="ISO-8859-1" ?><Servicos><cServico>` +
+			`<Codigo>04014</Codigo><Valor>0,00</Valor><PrazoEntrega>0</PrazoEntrega><Erro>011</Erro>` +
+			`<MsgErro>CEP inicial e final pertencentes a Área de Risco</MsgErro></cServico></Servicos>`))
+	}))
+	defer srv.Close()
+	prev := correios.FreteEndpoint
+	correios.FreteEndpoint = srv.URL
+	defer func() { correios.FreteEndpoint = prev }()
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resp, err := correios.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, correios.ErrAreaDeRiscoCEPs, resp.Any().Erro.Codigo)
+}
+
+func TestErroMsgVazia(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	srv.SetServico(correiostest.Servico{Codigo: correios.SvcPACVarejo, Erro: correios.ErrCepDestinoInvalido})
+	srv.SetServico(correiostest.Servico{Codigo: correios.SvcSEDEXVarejo, Erro: correios.TipoErro(-777)})
+	r := correios.NewFreteRequest("01243000", "65299970").
+		SetServicos(correios.SvcPACVarejo, correios.SvcSEDEXVarejo)
+	r.Mode = correios.RequestModeSingle
+	resp, err := srv.Client().CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, "CEP de destino inválido", resp.Servicos[correios.SvcPACVarejo].ErroMsg)
+	assert.Equal(t, "erro desconhecido (-777)", resp.Servicos[correios.SvcSEDEXVarejo].ErroMsg)
+}
+
+func TestParseErr(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<?xml version="1.0" encoding="ISO-8859-1" ?><Servicos><cServico>` +
+			`<Codigo>04014</Codigo><Valor>R$ 42</Valor><PrazoEntrega>2</PrazoEntrega><ValorSemAdicionais>1.234,56</ValorSemAdicionais>` +
+			`<Erro>0</Erro><MsgErro></MsgErro></cServico></Servicos>`))
+	}))
+	defer srv.Close()
+	c := &correios.Client{FreteEndpoint: srv.URL}
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resp, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	svc := resp.Any()
+	assert.Error(t, svc.ParseErr)
+	assert.Contains(t, svc.ParseErr.Error(), "Valor")
+	assert.Equal(t, "1234.56", svc.PrecoSemAdicionais.String())
+	_, ok := resp.Cheapest()
+	assert.False(t, ok)
+}
+
+func TestClone(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970")
+	r.Formato = correios.FormatoRoloCilindro
+	r.DiametroCm = decimal.NewFromInt(10)
+	r.MaoPropria = true
+	r.Formatos = map[correios.TipoServico]correios.Formato{correios.SvcPACVarejo: correios.FormatoEnvelope}
+	r2 := r.Clone()
+	assert.Equal(t, r, r2)
+	r2.Servicos[0] = correios.SvcSEDEX10Varejo
+	assert.Equal(t, correios.SvcSEDEXVarejo, r.Servicos[0])
+	r2.Formatos[correios.SvcPACVarejo] = correios.FormatoCaixaPacote
+	assert.Equal(t, correios.FormatoEnvelope, r.Formatos[correios.SvcPACVarejo])
+}
+
+func TestSetters(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970").
+		SetPesoKg(1.25).
+		SetDimensoesCm(30, 20, 10.5).
+		SetValorDeclaradoFloat(150.9)
+	assert.Equal(t, "1.25", r.PesoKg.String())
+	assert.Equal(t, "30", r.ComprimentoCm.String())
+	assert.Equal(t, "20", r.LarguraCm.String())
+	assert.Equal(t, "10.5", r.AlturaCm.String())
+	assert.Equal(t, "150.9", r.ValorDeclarado.String())
+	r.SetValorDeclarado(decimal.RequireFromString("99.99"))
+	assert.Equal(t, "99.99", r.ValorDeclarado.String())
+}
+
+func TestValuesRoloCilindro(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970").SetDimensoesCm(30, 20, 10)
+	v := r.Values()
+	assert.Equal(t, "20", v.Get("nVlLargura"))
+	assert.Equal(t, "10", v.Get("nVlAltura"))
+	assert.Empty(t, v.Get("nVlDiametro"))
+
+	r.Formato = correios.FormatoRoloCilindro
+	r.DiametroCm = decimal.NewFromInt(8)
+	v = r.Values()
+	assert.Equal(t, "0", v.Get("nVlLargura"))
+	assert.Equal(t, "0", v.Get("nVlAltura"))
+	assert.Equal(t, "8", v.Get("nVlDiametro"))
+	assert.Equal(t, "30", v.Get("nVlComprimento"))
+}
+
+func TestDefaultServicos(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970")
+	assert.Equal(t, []correios.TipoServico{correios.SvcSEDEXVarejo, correios.SvcPACVarejo}, r.Servicos)
+	correios.DefaultServicos = []correios.TipoServico{correios.SvcPACVarejo}
+	defer func() { correios.DefaultServicos = nil }()
+	r = correios.NewFreteRequest("01243000", "65299970")
+	assert.Equal(t, []correios.TipoServico{correios.SvcPACVarejo}, r.Servicos)
+	// o request não compartilha o slice
+	r.Servicos[0] = correios.SvcSEDEXVarejo
+	assert.Equal(t, correios.SvcPACVarejo, correios.DefaultServicos[0])
+}
+
+func TestPesoGramas(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970").SetPesoGramas(1250)
+	assert.Equal(t, "1.25", r.PesoKg.String())
+	assert.Equal(t, 1250, r.PesoGramas())
+	r.SetPesoGramas(120)
+	assert.Equal(t, "0.3", r.PesoKg.String())
+	assert.Equal(t, 300, r.PesoGramas())
+}
+
+func TestValorDeclaradoCentavos(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970").SetValorDeclaradoCentavos(15090)
+	assert.Equal(t, "150.9", r.ValorDeclarado.String())
+	assert.Equal(t, "0.01", correios.ReaisFromCentavos(1).String())
+	assert.NoError(t, r.Validate())
+	r.SetValorDeclaradoCentavos(1000001)
+	var ve *correios.ValidacaoError
+	assert.True(t, errors.As(r.Validate(), &ve))
+	assert.Equal(t, correios.ErrValorDeclaradoAlto10k, ve.Codigo)
+}
+
+func TestEntregaInfo(t *testing.T) {
+	s := correios.ServicoResponse{EntregaDomiciliar: true, EntregaSabado: true}
+	assert.Equal(t, "entrega domiciliar, entrega aos sábados", s.EntregaInfo())
+	s.EntregaSabado = false
+	assert.Equal(t, "entrega domiciliar, sem entrega aos sábados", s.EntregaInfo())
+	s.EntregaDomiciliar = false
+	assert.Equal(t, "sem entrega domiciliar, sem entrega aos sábados", s.EntregaInfo())
+}
+
+func TestDataEntregaEstimada(t *testing.T) {
+	// quinta-feira
+	from := time.Date(2021, 6, 3, 10, 0, 0, 0, time.UTC)
+	s := correios.ServicoResponse{PrazoEntregaDias: 2}
+	assert.Equal(t, 48*time.Hour, s.PrazoEntrega())
+	assert.Equal(t, time.Date(2021, 6, 7, 10, 0, 0, 0, time.UTC), s.DataEntregaEstimada(from))
+	s.EntregaSabado = true
+	assert.Equal(t, time.Date(2021, 6, 5, 10, 0, 0, 0, time.UTC), s.DataEntregaEstimada(from))
+	s.PrazoEntregaDias = 0
+	assert.Equal(t, from, s.DataEntregaEstimada(from))
+}
+
+func TestContextCancel(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	srv.SetDelay(5 * time.Second)
+	c := srv.Client()
+
+	// request único
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r := correios.NewFreteRequest("01243000", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	start := time.Now()
+	resp, err := c.CalcularFrete(ctx, r)
+	assert.Nil(t, resp)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, time.Since(start) < time.Second)
+
+	// requests separados por serviço
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel2)
+	r.SetServicos(correios.SvcSEDEXVarejo, correios.SvcPACVarejo, correios.SvcSEDEX10Varejo)
+	r.Mode = correios.RequestModeSingle
+	start = time.Now()
+	resp, err = c.CalcularFrete(ctx2, r)
+	assert.Nil(t, resp)
+	assert.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// context já cancelado: nenhum request é enviado
+	n := len(srv.Requests())
+	resp, err = c.CalcularFrete(ctx2, r)
+	assert.Nil(t, resp)
+	assert.Equal(t, context.Canceled, err)
+	assert.Len(t, srv.Requests(), n)
+}
+
+func TestContextCancelParcial(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("nCdServico") != string(correios.SvcSEDEXVarejo) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte(freteXML))
+	}))
+	defer srv.Close()
+	c := &correios.Client{FreteEndpoint: srv.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r := correios.NewFreteRequest("01243000", "65299970").
+		SetServicos(correios.SvcSEDEXVarejo, correios.SvcPACVarejo)
+	r.Mode = correios.RequestModeSingle
+	resp, err := c.CalcularFrete(ctx, r)
+	// o serviço já consultado é mantido
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, "42.5", resp.Servicos[correios.SvcSEDEXVarejo].Preco.String())
+		assert.Equal(t, correios.ErrIndeterminado, resp.Servicos[correios.SvcPACVarejo].Erro.Codigo)
+	}
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	var se *correios.ServicosError
+	if assert.True(t, errors.As(err, &se)) {
+		assert.Len(t, se.Erros, 1)
+		assert.NotNil(t, se.Erros[correios.SvcPACVarejo])
+	}
 }
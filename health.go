@@ -0,0 +1,77 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CEP utilizado nas verificações de saúde (Praça da Sé, São Paulo)
+const healthCEP = "01001000"
+
+// Health verifica se os serviços de frete e de CEP dos Correios estão
+// respondendo (ver Client.Health)
+func Health(ctx context.Context) error {
+	return DefaultClient.Health(ctx)
+}
+
+// Health verifica se os serviços de frete e de CEP dos Correios estão
+// respondendo. Retorna o primeiro erro encontrado.
+func (c *Client) Health(ctx context.Context) error {
+	if err := c.HealthFrete(ctx); err != nil {
+		return err
+	}
+	return c.HealthCEP(ctx)
+}
+
+// HealthFrete envia uma consulta de frete mínima e conhecida (SEDEX, mesmo
+// CEP de origem e destino) ao FreteEndpoint, com as credenciais do Client,
+// e verifica se o serviço foi precificado. Somente um trecho sem cobertura
+// (ver SemCobertura) é aceito como erro; erros de credenciais (ver
+// IsErroCredenciais) e os demais erros indicam falha. FallbackFunc e Retry
+// não são utilizados.
+func (c *Client) HealthFrete(ctx context.Context) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	req := NewFreteRequest(healthCEP, healthCEP).SetServicos(SvcSEDEXVarejo)
+	req.CdEmpresa, req.DsSenha = c.CdEmpresa, c.DsSenha
+	resp, err := c.requestFrete(ctx, req.Values())
+	if err != nil {
+		return err
+	}
+	svc, ok := resp.Servicos[SvcSEDEXVarejo]
+	if !ok {
+		return errors.New("correios: health: resposta sem o serviço " + string(SvcSEDEXVarejo))
+	}
+	if svc.Erro != nil && !svc.SemCobertura() {
+		return fmt.Errorf("correios: health: %w", svc.Erro)
+	}
+	return nil
+}
+
+// HealthCEP consulta um CEP conhecido no CEPURL (sem utilizar o CEPCache
+// ou o ViaCEP)
+func (c *Client) HealthCEP(ctx context.Context) error {
+	_, err := c.buscaEndereco(ctx, healthCEP)
+	return err
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/gabstv/correios/correiostest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	cep := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(cepJSON))
+	}))
+	defer cep.Close()
+	c := srv.Client()
+	c.CEPURL = cep.URL
+	assert.NoError(t, c.Health(context.Background()))
+	q := srv.Requests()[0]
+	assert.Equal(t, string(correios.SvcSEDEXVarejo), q.Get("nCdServico"))
+
+	srv.SetErro(correios.SvcSEDEXVarejo, correios.ErrSistemaIndisponivel)
+	err := c.Health(context.Background())
+	var se *correios.ServicoResponseError
+	assert.True(t, errors.As(err, &se))
+
+	// credenciais inválidas
+	c.WithCredentials("08082650", "errada")
+	srv.SetErro(correios.SvcSEDEXVarejo, correios.ErrSemContrato)
+	err = c.HealthFrete(context.Background())
+	assert.True(t, errors.Is(err, correios.ErrCredenciais))
+	q = srv.Requests()[len(srv.Requests())-1]
+	assert.Equal(t, "08082650", q.Get("nCdEmpresa"))
+
+	// trecho sem cobertura não é uma falha
+	srv.SetErro(correios.SvcSEDEXVarejo, correios.ErrServicoIndisponivelTrecho)
+	assert.NoError(t, c.HealthFrete(context.Background()))
+
+	// a FallbackFunc não mascara a falha
+	srv.Close()
+	c.FallbackFunc = func(ctx context.Context, v url.Values) (*correios.FreteResponse, error) {
+		return &correios.FreteResponse{}, nil
+	}
+	assert.Error(t, c.HealthFrete(context.Background()))
+	assert.NoError(t, c.HealthCEP(context.Background()))
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IBGEURL is the base URL of the IBGE localities API, used by CodigoIBGE
+// (unless Client.IBGEURL is set). The municipalities of each UF are
+// requested from IBGEURL + "{UF}/municipios".
+var IBGEURL = "https://servicodados.ibge.gov.br/api/v1/localidades/estados/"
+
+// ibgeMunicipio is a single entry of the IBGE municipalities list.
+type ibgeMunicipio struct {
+	ID   int    `json:"id"`
+	Nome string `json:"nome"`
+}
+
+// ibgeCache stores the municipalities of each UF (normalized name -> code).
+type ibgeCache struct {
+	l  sync.Mutex
+	uf map[string]map[string]string
+}
+
+// CodigoIBGE returns the 7 digit IBGE municipality code of a city (e.g.
+// "3509502" for Campinas/SP), required by NF-e and other tax documents. The
+// Correios don't return it, so the IBGE API (IBGEURL) is used. Cities are
+// matched ignoring case, accents, hyphens and apostrophes. ErrNoResults is
+// returned if the city is not found in the UF.
+func CodigoIBGE(ctx context.Context, uf, cidade string) (string, error) {
+	return DefaultClient.CodigoIBGE(ctx, uf, cidade)
+}
+
+// CodigoIBGE returns the IBGE municipality code of a city using the Client
+// configuration. The municipalities of each UF are requested once and kept
+// in memory for the lifetime of the Client.
+func (c *Client) CodigoIBGE(ctx context.Context, uf, cidade string) (string, error) {
+	uf = strings.ToUpper(strings.TrimSpace(uf))
+	if len(uf) != 2 {
+		return "", errors.New("correios: invalid UF")
+	}
+	municipios, err := c.municipiosIBGE(ctx, uf)
+	if err != nil {
+		return "", err
+	}
+	if cod, ok := municipios[normalizarCidade(cidade)]; ok {
+		return cod, nil
+	}
+	return "", ErrNoResults
+}
+
+// ConsultaCEPDetalhadoIBGE works like ConsultaCEPDetalhado, also filling
+// the IBGE municipality code (see CodigoIBGE). It requires an additional
+// request per UF.
+func ConsultaCEPDetalhadoIBGE(ctx context.Context, cep string) (*CEPResultDetalhado, error) {
+	return DefaultClient.ConsultaCEPDetalhadoIBGE(ctx, cep)
+}
+
+// ConsultaCEPDetalhadoIBGE works like ConsultaCEPDetalhadoIBGE using the
+// Client configuration (CEPURL, IBGEURL and the municipalities cache).
+func (c *Client) ConsultaCEPDetalhadoIBGE(ctx context.Context, cep string) (*CEPResultDetalhado, error) {
+	result, err := c.ConsultaCEPDetalhado(ctx, cep)
+	if err != nil {
+		return nil, err
+	}
+	result.IBGE, err = c.CodigoIBGE(ctx, result.UF, result.Cidade)
+	if err != nil {
+		return nil, fmt.Errorf("correios: ibge: %w", err)
+	}
+	return result, nil
+}
+
+func (c *Client) municipiosIBGE(ctx context.Context, uf string) (map[string]string, error) {
+	c.ibge.l.Lock()
+	m, ok := c.ibge.uf[uf]
+	c.ibge.l.Unlock()
+	if ok {
+		return m, nil
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	u := strings.TrimRight(c.ibgeURL(), "/") + "/" + uf + "/municipios"
+	rq0, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	cresp, err := c.do(rq0)
+	if err != nil {
+		return nil, &TransporteError{Err: err}
+	}
+	defer cresp.Body.Close()
+	if cresp.StatusCode != http.StatusOK {
+		return nil, statusError(cresp)
+	}
+	var list []ibgeMunicipio
+	if err := json.NewDecoder(cresp.Body).Decode(&list); err != nil {
+		return nil, &DecodeError{Err: fmt.Errorf("decode json error: %w", err)}
+	}
+	if len(list) == 0 {
+		return nil, ErrNoResults
+	}
+	m = make(map[string]string, len(list))
+	for _, v := range list {
+		m[normalizarCidade(v.Nome)] = strconv.Itoa(v.ID)
+	}
+	c.ibge.l.Lock()
+	if c.ibge.uf == nil {
+		c.ibge.uf = make(map[string]map[string]string)
+	}
+	c.ibge.uf[uf] = m
+	c.ibge.l.Unlock()
+	return m, nil
+}
+
+var cidadeReplacer = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "ê", "e", "è", "e", "ë", "e",
+	"í", "i", "î", "i", "ì", "i", "ï", "i",
+	"ó", "o", "ô", "o", "õ", "o", "ò", "o", "ö", "o",
+	"ú", "u", "û", "u", "ù", "u", "ü", "u",
+	"ç", "c", "ñ", "n",
+	"-", " ", "'", "", "’", "", "`", "",
+)
+
+// normalizarCidade returns the city name in lower case, without accents,
+// hyphens, apostrophes or repeated spaces.
+func normalizarCidade(v string) string {
+	v = cidadeReplacer.Replace(strings.ToLower(v))
+	return strings.Join(strings.Fields(v), " ")
+}
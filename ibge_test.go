@@ -0,0 +1,104 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+)
+
+func withIBGEServer(t *testing.T, n *int) func() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*n++
+		if r.URL.Path != "/SP/municipios" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"id":3509502,"nome":"Campinas"},{"id":3550308,"nome":"São Paulo"},{"id":3515103,"nome":"Embu-Guaçu"}]`))
+	}))
+	prev := correios.IBGEURL
+	correios.IBGEURL = srv.URL + "/"
+	return func() {
+		correios.IBGEURL = prev
+		srv.Close()
+	}
+}
+
+func TestCodigoIBGE(t *testing.T) {
+	n := 0
+	defer withIBGEServer(t, &n)()
+	c := &correios.Client{}
+	cod, err := c.CodigoIBGE(context.Background(), "sp", "Sao Paulo")
+	assert.NoError(t, err)
+	assert.Equal(t, "3550308", cod)
+	cod, err = c.CodigoIBGE(context.Background(), "SP", " embu  guacu")
+	assert.NoError(t, err)
+	assert.Equal(t, "3515103", cod)
+	_, err = c.CodigoIBGE(context.Background(), "SP", "Atlântida")
+	assert.Equal(t, correios.ErrNoResults, err)
+	// the municipalities of SP are requested only once
+	assert.Equal(t, 1, n)
+	_, err = c.CodigoIBGE(context.Background(), "XX", "Campinas")
+	assert.Equal(t, correios.ErrNoResults, err)
+	_, err = c.CodigoIBGE(context.Background(), "", "Campinas")
+	assert.Error(t, err)
+}
+
+func TestConsultaCEPDetalhadoIBGE(t *testing.T) {
+	n := 0
+	defer withIBGEServer(t, &n)()
+	defer withCEPServer(t, cepJSON)()
+	r, err := correios.ConsultaCEPDetalhadoIBGE(context.Background(), "13056-535")
+	assert.NoError(t, err)
+	assert.Equal(t, "Campinas", r.Cidade)
+	assert.Equal(t, "3509502", r.IBGE)
+}
+
+func TestConsultaCEPDetalhadoIBGEClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cep":
+			w.Write([]byte(cepJSON))
+		case "/ibge/SP/municipios":
+			w.Write([]byte(`[{"id":3509502,"nome":"Campinas"}]`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	c := &correios.Client{CEPURL: srv.URL + "/cep", IBGEURL: srv.URL + "/ibge/"}
+	r, err := c.ConsultaCEPDetalhadoIBGE(context.Background(), "13056-535")
+	assert.NoError(t, err)
+	assert.Equal(t, "3509502", r.IBGE)
+
+	c = &correios.Client{IBGEURL: srv.URL + "/fora/"}
+	_, err = c.CodigoIBGE(context.Background(), "SP", "Campinas")
+	var te *correios.TransporteError
+	if assert.True(t, errors.As(err, &te)) {
+		assert.Equal(t, http.StatusServiceUnavailable, te.StatusCode())
+	}
+}
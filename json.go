@@ -0,0 +1,114 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MarshalJSON serializa os serviços como uma lista ordenada pelo código do
+// serviço (ver Sorted). Timestamp, Origem e AjusteValorDeclarado são
+// omitidos quando vazios.
+func (r FreteResponse) MarshalJSON() ([]byte, error) {
+	var ts *time.Time
+	if !r.Timestamp.IsZero() {
+		ts = &r.Timestamp
+	}
+	return json.Marshal(struct {
+		Servicos  []ServicoResponse     `json:"servicos"`
+		Timestamp *time.Time            `json:"timestamp,omitempty"`
+		Origem    Origem                `json:"origem,omitempty"`
+		Ajuste    *AjusteValorDeclarado `json:"ajusteValorDeclarado,omitempty"`
+	}{
+		Servicos:  r.Sorted(),
+		Timestamp: ts,
+		Origem:    r.Origem,
+		Ajuste:    r.AjusteValorDeclarado,
+	})
+}
+
+// MarshalJSON serializa os preços como strings com duas casas decimais
+// (ex.: "42.50").
+func (s ServicoResponse) MarshalJSON() ([]byte, error) {
+	type alias ServicoResponse
+	return json.Marshal(struct {
+		alias
+		Preco                 string `json:"preco"`
+		PrecoSemAdicionais    string `json:"precoSemAdicionais"`
+		PrecoMaoPropria       string `json:"precoMaoPropria"`
+		PrecoAvisoRecebimento string `json:"precoAvisoRecebimento"`
+		PrecoValorDeclarado   string `json:"precoValorDeclarado"`
+	}{
+		alias:                 alias(s),
+		Preco:                 s.Preco.StringFixed(2),
+		PrecoSemAdicionais:    s.PrecoSemAdicionais.StringFixed(2),
+		PrecoMaoPropria:       s.PrecoMaoPropria.StringFixed(2),
+		PrecoAvisoRecebimento: s.PrecoAvisoRecebimento.StringFixed(2),
+		PrecoValorDeclarado:   s.PrecoValorDeclarado.StringFixed(2),
+	})
+}
+
+// ServicoResponseJSON é uma visão de ServicoResponse para serialização em
+// JSON com os preços como números (ex.: 42.50) em vez de strings. Os
+// números são gerados a partir do decimal, sem perda de precisão.
+type ServicoResponseJSON struct {
+	Tipo                  TipoServico           `json:"codigo"`
+	Preco                 json.Number           `json:"preco"`
+	PrazoEntregaDias      int                   `json:"prazoEntregaDias"`
+	PrecoSemAdicionais    json.Number           `json:"precoSemAdicionais"`
+	PrecoMaoPropria       json.Number           `json:"precoMaoPropria"`
+	PrecoAvisoRecebimento json.Number           `json:"precoAvisoRecebimento"`
+	PrecoValorDeclarado   json.Number           `json:"precoValorDeclarado"`
+	EntregaDomiciliar     bool                  `json:"entregaDomiciliar"`
+	EntregaSabado         bool                  `json:"entregaSabado"`
+	Erro                  *ServicoResponseError `json:"erro,omitempty"`
+	ErroMsg               string                `json:"erroMsg,omitempty"`
+}
+
+// ToJSONView retorna a visão de s com os preços como números JSON com duas
+// casas decimais
+func (s ServicoResponse) ToJSONView() ServicoResponseJSON {
+	return ServicoResponseJSON{
+		Tipo:                  s.Tipo,
+		Preco:                 json.Number(s.Preco.StringFixed(2)),
+		PrazoEntregaDias:      s.PrazoEntregaDias,
+		PrecoSemAdicionais:    json.Number(s.PrecoSemAdicionais.StringFixed(2)),
+		PrecoMaoPropria:       json.Number(s.PrecoMaoPropria.StringFixed(2)),
+		PrecoAvisoRecebimento: json.Number(s.PrecoAvisoRecebimento.StringFixed(2)),
+		PrecoValorDeclarado:   json.Number(s.PrecoValorDeclarado.StringFixed(2)),
+		EntregaDomiciliar:     s.EntregaDomiciliar,
+		EntregaSabado:         s.EntregaSabado,
+		Erro:                  s.Erro,
+		ErroMsg:               s.ErroMsg,
+	}
+}
+
+// ToJSONView retorna os serviços (ver Sorted) com os preços como números
+// JSON
+func (r *FreteResponse) ToJSONView() []ServicoResponseJSON {
+	list := r.Sorted()
+	out := make([]ServicoResponseJSON, len(list))
+	for i, v := range list {
+		out[i] = v.ToJSONView()
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreteResponseMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(testFreteResponse())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"servicos":[
+		{"codigo":"04014","preco":"42.50","prazoEntregaDias":2,"precoSemAdicionais":"0.00","precoMaoPropria":"0.00",
+		 "precoAvisoRecebimento":"0.00","precoValorDeclarado":"0.00","entregaDomiciliar":false,"entregaSabado":false},
+		{"codigo":"04510","preco":"21.90","prazoEntregaDias":7,"precoSemAdicionais":"0.00","precoMaoPropria":"0.00",
+		 "precoAvisoRecebimento":"0.00","precoValorDeclarado":"0.00","entregaDomiciliar":false,"entregaSabado":false},
+		{"codigo":"40215","preco":"0.00","prazoEntregaDias":0,"precoSemAdicionais":"0.00","precoMaoPropria":"0.00",
+		 "precoAvisoRecebimento":"0.00","precoValorDeclarado":"0.00","entregaDomiciliar":false,"entregaSabado":false,
+		 "erro":{"codigo":-6},"erroMsg":"Serviço indisponível para o trecho informado"}
+	]}`, string(b))
+}
+
+func TestToJSONView(t *testing.T) {
+	s := correios.ServicoResponse{
+		Tipo:                correios.SvcSEDEXVarejo,
+		Preco:               decimal.RequireFromString("42.5"),
+		PrazoEntregaDias:    2,
+		PrecoValorDeclarado: decimal.RequireFromString("12345678901234567.891"),
+	}
+	b, err := json.Marshal(s.ToJSONView())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"codigo":"04014","preco":42.50,"prazoEntregaDias":2,"precoSemAdicionais":0.00,"precoMaoPropria":0.00,
+		"precoAvisoRecebimento":0.00,"precoValorDeclarado":12345678901234567.89,"entregaDomiciliar":false,"entregaSabado":false}`, string(b))
+	assert.Contains(t, string(b), `"precoValorDeclarado":12345678901234567.89`)
+	assert.Len(t, testFreteResponse().ToJSONView(), 3)
+}
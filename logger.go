@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Logger recebe os dados de cada request HTTP enviado aos Correios (útil p/
+// métricas e p/ identificar requests lentos)
+type Logger interface {
+	LogRequest(info RequestInfo)
+}
+
+// LoggerFunc permite utilizar uma função como Logger
+type LoggerFunc func(info RequestInfo)
+
+// LogRequest chama f(info)
+func (f LoggerFunc) LogRequest(info RequestInfo) {
+	f(info)
+}
+
+// RequestInfo descreve um request HTTP enviado aos Correios
+type RequestInfo struct {
+	Method string
+	// URL do request; a senha (sDsSenha) é omitida
+	URL string
+	// StatusCode é 0 caso o request tenha falhado (ver Err)
+	StatusCode int
+	Elapsed    time.Duration
+	Err        error
+}
+
+// do envia o request utilizando o *http.Client do Client, aguardando o
+// RateLimiter e notificando o Logger (se definidos)
+func (c *Client) do(rq *http.Request) (*http.Response, error) {
+	if c.RateLimiter != nil {
+		if err := c.RateLimiter.Wait(rq.Context()); err != nil {
+			return nil, err
+		}
+	}
+	if c.Logger == nil {
+		return c.httpClient().Do(rq)
+	}
+	start := time.Now()
+	resp, err := c.httpClient().Do(rq)
+	info := RequestInfo{
+		Method:  rq.Method,
+		URL:     redactURL(rq.URL),
+		Elapsed: time.Since(start),
+		Err:     err,
+	}
+	if resp != nil {
+		info.StatusCode = resp.StatusCode
+	}
+	c.Logger.LogRequest(info)
+	return resp, err
+}
+
+// redactURL omite a senha da query string
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	if q.Get("sDsSenha") == "" {
+		return u.String()
+	}
+	q.Set("sDsSenha", "xxxxx")
+	u2 := *u
+	u2.RawQuery = q.Encode()
+	return u2.String()
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"expvar"
+	"strconv"
+)
+
+// Metricas recebe o resultado de cada consulta de frete do Client (ver
+// Client.Metricas), permitindo contar sucessos e erros por código (ex.: p/
+// identificar quais erros aumentam durante um incidente). As implementações
+// devem ser seguras p/ uso concorrente.
+type Metricas interface {
+	// IncFrete é chamada uma vez por chamada a CalcularFrete (inclusive as
+	// respostas obtidas do FreteCache); err é nil em caso de sucesso
+	IncFrete(err error)
+	// IncServico é chamada p/ cada serviço da resposta; codigo é 0 em caso
+	// de sucesso
+	IncServico(svc TipoServico, codigo TipoErro)
+}
+
+// MetricasExpvar implementa Metricas utilizando um *expvar.Map, publicado em
+// /debug/vars. As chaves são:
+//
+//	frete_ok, frete_erro      consultas com e sem sucesso
+//	servico_{codigo}_ok       serviços precificados (ex.: servico_04014_ok)
+//	erro_{codigo}             serviços com erro (ex.: erro_-3, erro_8)
+type MetricasExpvar struct {
+	m *expvar.Map
+}
+
+// NewMetricasExpvar publica um *expvar.Map com o nome informado. Assim como
+// expvar.NewMap, causa panic caso o nome já tenha sido publicado.
+func NewMetricasExpvar(nome string) *MetricasExpvar {
+	return &MetricasExpvar{m: expvar.NewMap(nome)}
+}
+
+// NewMetricasExpvarMap utiliza um *expvar.Map já existente (não publicado
+// por NewMetricasExpvarMap), ex.: um map publicado pela aplicação ou um
+// new(expvar.Map).Init() em testes
+func NewMetricasExpvarMap(m *expvar.Map) *MetricasExpvar {
+	return &MetricasExpvar{m: m}
+}
+
+// Map retorna o *expvar.Map utilizado
+func (e *MetricasExpvar) Map() *expvar.Map {
+	return e.m
+}
+
+// IncFrete implementa Metricas
+func (e *MetricasExpvar) IncFrete(err error) {
+	if err != nil {
+		e.m.Add("frete_erro", 1)
+		return
+	}
+	e.m.Add("frete_ok", 1)
+}
+
+// IncServico implementa Metricas
+func (e *MetricasExpvar) IncServico(svc TipoServico, codigo TipoErro) {
+	if codigo == 0 {
+		e.m.Add("servico_"+string(svc)+"_ok", 1)
+		return
+	}
+	e.m.Add("erro_"+strconv.Itoa(int(codigo)), 1)
+}
+
+// medirFrete notifica Metricas (se definida) com o resultado da consulta
+func (c *Client) medirFrete(resp *FreteResponse, err error) {
+	if c.Metricas == nil {
+		return
+	}
+	c.Metricas.IncFrete(err)
+	if resp == nil {
+		return
+	}
+	for _, v := range resp.Lista() {
+		var codigo TipoErro
+		if v.Erro != nil {
+			codigo = v.Erro.Codigo
+		}
+		c.Metricas.IncServico(v.Tipo, codigo)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gabstv/correios"
+	"github.com/gabstv/correios/correiostest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricasExpvar(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	srv.SetErro(correios.SvcPACVarejo, correios.ErrCepDestinoInvalido)
+	m := correios.NewMetricasExpvarMap(new(expvar.Map).Init())
+	c := srv.Client()
+	c.Metricas = m
+	r := correios.NewFreteRequest("01243000", "65299970")
+	_, err := c.CalcularFrete(context.Background(), r)
+	assert.NoError(t, err)
+	// a consulta dividida por serviço é contada uma única vez
+	assert.Equal(t, "1", m.Map().Get("frete_ok").String())
+	assert.Equal(t, "1", m.Map().Get("servico_04014_ok").String())
+	assert.Equal(t, "1", m.Map().Get("erro_-3").String())
+	assert.Nil(t, m.Map().Get("frete_erro"))
+
+	srv.Close()
+	_, err = c.CalcularFrete(context.Background(), r.SetServicos(correios.SvcSEDEXVarejo))
+	assert.Error(t, err)
+	assert.Equal(t, "1", m.Map().Get("frete_erro").String())
+}
+
+func TestNewMetricasExpvar(t *testing.T) {
+	// o nome é único a cada execução (go test -count=N)
+	nome := fmt.Sprintf("%s_%d", t.Name(), time.Now().UnixNano())
+	m := correios.NewMetricasExpvar(nome)
+	assert.Equal(t, m.Map(), expvar.Get(nome))
+	m.IncFrete(nil)
+	assert.Equal(t, "1", m.Map().Get("frete_ok").String())
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"sync"
+)
+
+// CalcularFreteMultiOrigem calcula o frete de req para cada um dos CEPs de
+// origem (ex.: vários centros de distribuição), em paralelo. O resultado e
+// os erros são indexados pelo CEP de origem informado; uma origem com erro
+// não interrompe as demais. Ver MelhorOrigem.
+func CalcularFreteMultiOrigem(ctx context.Context, origens []string, req *FreteRequest) (map[string]*FreteResponse, map[string]error) {
+	return DefaultClient.CalcularFreteMultiOrigem(ctx, origens, req)
+}
+
+// CalcularFreteMultiOrigem funciona como a função CalcularFreteMultiOrigem,
+// utilizando as configurações do Client
+func (c *Client) CalcularFreteMultiOrigem(ctx context.Context, origens []string, req *FreteRequest) (map[string]*FreteResponse, map[string]error) {
+	results := make(map[string]*FreteResponse, len(origens))
+	errs := make(map[string]error)
+	l := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	seen := make(map[string]bool, len(origens))
+	for _, origem := range origens {
+		if seen[origem] {
+			continue
+		}
+		seen[origem] = true
+		clone := req.Clone()
+		clone.CepOrigem = origem
+		wg.Add(1)
+		go func(origem string, r *FreteRequest) {
+			defer wg.Done()
+			resp, err := c.CalcularFrete(ctx, r)
+			l.Lock()
+			defer l.Unlock()
+			if err != nil {
+				errs[origem] = err
+				return
+			}
+			results[origem] = resp
+		}(origem, clone)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+// MelhorOrigem retorna a origem e o serviço mais barato (ver Cheapest)
+// entre as respostas de CalcularFreteMultiOrigem. Em caso de empate, o
+// menor prazo e depois o menor CEP de origem são escolhidos. ok é false
+// caso nenhum serviço tenha sido precificado com sucesso.
+func MelhorOrigem(resps map[string]*FreteResponse) (origem string, svc ServicoResponse, ok bool) {
+	for o, resp := range resps {
+		if resp == nil {
+			continue
+		}
+		v, vok := resp.Cheapest()
+		if !vok {
+			continue
+		}
+		if !ok || v.Preco.LessThan(svc.Preco) ||
+			(v.Preco.Equal(svc.Preco) && v.PrazoEntregaDias < svc.PrazoEntregaDias) ||
+			(v.Preco.Equal(svc.Preco) && v.PrazoEntregaDias == svc.PrazoEntregaDias && o < origem) {
+			origem = o
+			svc = v
+			ok = true
+		}
+	}
+	return
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/gabstv/correios/correiostest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcularFreteMultiOrigem(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("sCepOrigem") {
+		case "01243000":
+			w.Write(correiostest.FreteXML(correiostest.SEDEX))
+		case "13056535":
+			sedex := correiostest.SEDEX
+			sedex.Valor = "30,00"
+			w.Write(correiostest.FreteXML(sedex))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	c := &correios.Client{FreteEndpoint: srv.URL}
+	r := correios.NewFreteRequest("", "65299970").SetServicos(correios.SvcSEDEXVarejo)
+	resps, errs := c.CalcularFreteMultiOrigem(context.Background(), []string{"01243000", "13056535", "99999999"}, r)
+	assert.Len(t, resps, 2)
+	assert.Len(t, errs, 1)
+	assert.Error(t, errs["99999999"])
+	assert.Equal(t, "42.5", resps["01243000"].Any().Preco.String())
+	assert.Equal(t, "", r.CepOrigem)
+
+	origem, svc, ok := correios.MelhorOrigem(resps)
+	assert.True(t, ok)
+	assert.Equal(t, "13056535", origem)
+	assert.Equal(t, "30", svc.Preco.String())
+
+	_, _, ok = correios.MelhorOrigem(nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PrazoEndpoint o endpoint a ser utilizado para calcular somente o prazo
+var PrazoEndpoint = "https://ws.correios.com.br/calculador/CalcPrecoPrazo.asmx/CalcPrazo"
+
+// PrazoResponse representa o prazo retornado para um tipo de serviço
+type PrazoResponse struct {
+	Tipo              TipoServico
+	PrazoEntregaDias  int
+	EntregaDomiciliar bool
+	EntregaSabado     bool
+	Erro              *ServicoResponseError
+	ErroMsg           string
+}
+
+// CalcularPrazo consulta somente o prazo de entrega (em dias úteis) dos
+// serviços informados. Não é necessário informar peso ou dimensões.
+func CalcularPrazo(ctx context.Context, cepOrigem, cepDestino string, servicos ...TipoServico) (map[TipoServico]PrazoResponse, error) {
+	return DefaultClient.CalcularPrazo(ctx, cepOrigem, cepDestino, servicos...)
+}
+
+// CalcularPrazo consulta somente o prazo de entrega (em dias úteis) dos
+// serviços informados utilizando as configurações do Client.
+func (c *Client) CalcularPrazo(ctx context.Context, cepOrigem, cepDestino string, servicos ...TipoServico) (map[TipoServico]PrazoResponse, error) {
+	if len(servicos) == 0 {
+		return nil, errors.New("nenhum serviço informado")
+	}
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	svcs := make([]string, len(servicos))
+	for k, v := range servicos {
+		svcs[k] = string(v)
+	}
+	v := url.Values{}
+	v.Set("nCdServico", strings.Join(svcs, ","))
+	v.Set("sCepOrigem", FilterCEP(cepOrigem))
+	v.Set("sCepDestino", FilterCEP(cepDestino))
+
+	rq0, err := http.NewRequestWithContext(ctx, http.MethodGet, c.prazoEndpoint()+"?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	c.setFreteHeaders(rq0)
+	cresp, err := c.do(rq0)
+	if err != nil {
+		return nil, &TransporteError{Err: err}
+	}
+	defer cresp.Body.Close()
+	if cresp.StatusCode != http.StatusOK {
+		return nil, statusError(cresp)
+	}
+	p := xml.NewDecoder(cresp.Body)
+	p.CharsetReader = CharsetReader
+
+	vlov := struct {
+		XMLName string        `xml:"cResultado"`
+		Values  []servicoResp `xml:"Servicos>cServico"`
+	}{}
+	if err := p.Decode(&vlov); err != nil {
+		return nil, &DecodeError{Err: fmt.Errorf("decode xml error: %w", err)}
+	}
+	output := make(map[TipoServico]PrazoResponse)
+	for _, v := range vlov.Values {
+		v2 := PrazoResponse{
+			Tipo:              TipoServico(v.Codigo),
+			PrazoEntregaDias:  v.PrazoEntrega,
+			EntregaDomiciliar: (v.EntregaDomiciliar == "S"),
+			EntregaSabado:     (v.EntregaSabado == "S"),
+		}
+		if v.Erro != 0 {
+			v2.Erro = &ServicoResponseError{
+				Codigo: TipoErro(v.Erro),
+			}
+			v2.ErroMsg = strings.TrimSpace(v.MsgErro)
+			if v2.ErroMsg == "" {
+				// os Correios às vezes não enviam a mensagem
+				v2.ErroMsg = v2.Erro.Codigo.String()
+			}
+		}
+		output[v2.Tipo] = v2
+	}
+	return output, nil
+}
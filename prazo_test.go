@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+)
+
+const prazoXML = `<?xml version="1.0" encoding="utf-8"?>
+<cResultado xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns="http://tempuri.org/">
+  <Servicos>
+    <cServico>
+      <Codigo>04014</Codigo>
+      <PrazoEntrega>2</PrazoEntrega>
+      <EntregaDomiciliar>S</EntregaDomiciliar>
+      <EntregaSabado>S</EntregaSabado>
+      <Erro />
+      <MsgErro />
+    </cServico>
+    <cServico>
+      <Codigo>04510</Codigo>
+      <PrazoEntrega>0</PrazoEntrega>
+      <EntregaDomiciliar />
+      <EntregaSabado />
+      <Erro>008</Erro>
+      <MsgErro>Serviço indisponível para o trecho informado</MsgErro>
+    </cServico>
+  </Servicos>
+</cResultado>`
+
+func TestCalcularPrazo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "04014,04510", r.URL.Query().Get("nCdServico"))
+		assert.Equal(t, "01243000", r.URL.Query().Get("sCepOrigem"))
+		w.Write([]byte(prazoXML))
+	}))
+	defer srv.Close()
+	prev := correios.PrazoEndpoint
+	correios.PrazoEndpoint = srv.URL
+	defer func() { correios.PrazoEndpoint = prev }()
+	resp, err := correios.CalcularPrazo(context.Background(), "01243-000", "65299970",
+		correios.SvcSEDEXVarejo, correios.SvcPACVarejo)
+	assert.NoError(t, err)
+	assert.Len(t, resp, 2)
+	assert.Equal(t, 2, resp[correios.SvcSEDEXVarejo].PrazoEntregaDias)
+	assert.True(t, resp[correios.SvcSEDEXVarejo].EntregaSabado)
+	assert.Nil(t, resp[correios.SvcSEDEXVarejo].Erro)
+	assert.Equal(t, correios.ErrServicoIndisponivelTrecho2, resp[correios.SvcPACVarejo].Erro.Codigo)
+}
+
+func TestCalcularPrazoErros(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/502":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/html":
+			w.Write([]byte("<html><body>indisponível"))
+		default:
+			w.Write([]byte(`<cResultado><Servicos><cServico><Codigo>04510</Codigo>` +
+				`<Erro>-3</Erro><MsgErro /></cServico></Servicos></cResultado>`))
+		}
+	}))
+	defer srv.Close()
+	c := &correios.Client{PrazoEndpoint: srv.URL}
+	resp, err := c.CalcularPrazo(context.Background(), "01243000", "65299970", correios.SvcPACVarejo)
+	assert.NoError(t, err)
+	// a mensagem vazia é preenchida com a descrição do código
+	assert.Equal(t, correios.ErrCepDestinoInvalido.String(), resp[correios.SvcPACVarejo].ErroMsg)
+
+	c.PrazoEndpoint = srv.URL + "/502"
+	_, err = c.CalcularPrazo(context.Background(), "01243000", "65299970", correios.SvcPACVarejo)
+	var te *correios.TransporteError
+	if assert.True(t, errors.As(err, &te)) {
+		assert.Equal(t, http.StatusBadGateway, te.StatusCode())
+	}
+
+	c.PrazoEndpoint = srv.URL + "/html"
+	_, err = c.CalcularPrazo(context.Background(), "01243000", "65299970", correios.SvcPACVarejo)
+	var de *correios.DecodeError
+	assert.True(t, errors.As(err, &de))
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	// RastreioEndpoint o endpoint do SRO (rastreamento de objetos)
+	RastreioEndpoint = "https://websro.correios.com.br/sro_bin/sroii_xml.eventos"
+	// RastreioUsuario e RastreioSenha são as credenciais do SRO; as
+	// credenciais padrão são públicas
+	RastreioUsuario = "ECT"
+	RastreioSenha   = "SRO"
+	// ErrCodigoRastreioInvalido é retornado quando o código de rastreio não
+	// possui o formato AA123456789BR
+	ErrCodigoRastreioInvalido = errors.New("correios: código de rastreio inválido")
+)
+
+// fusoBrasilia é o fuso utilizado nas datas dos eventos do SRO
+var fusoBrasilia = time.FixedZone("BRT", -3*60*60)
+
+// RastreioResult é o resultado do rastreamento de um objeto
+type RastreioResult struct {
+	Codigo  string           `json:"codigo"`
+	Eventos []RastreioEvento `json:"eventos"`
+	// Erro é preenchido por RastrearVarios quando o objeto não pôde ser
+	// rastreado (ex.: objeto não encontrado)
+	Erro string `json:"erro,omitempty"`
+}
+
+// RastreioMaxObjetos é o número máximo de objetos por request ao SRO
+const RastreioMaxObjetos = 50
+
+// RastreioEvento é um evento (postagem, encaminhamento, entrega...) de um
+// objeto. Os eventos são retornados do mais recente p/ o mais antigo.
+type RastreioEvento struct {
+	Tipo       string    `json:"tipo"`
+	Status     string    `json:"status"`
+	Data       time.Time `json:"data"`
+	Descricao  string    `json:"descricao"`
+	Comentario string    `json:"comentario"`
+	Local      string    `json:"local"`
+	CEP        string    `json:"cep"`
+	Cidade     string    `json:"cidade"`
+	UF         string    `json:"uf"`
+}
+
+// xml wrapper for RastreioResult
+type sroObjeto struct {
+	Numero  string `xml:"numero"`
+	Erro    string `xml:"erro"`
+	Eventos []struct {
+		Tipo       string `xml:"tipo"`
+		Status     string `xml:"status"`
+		Data       string `xml:"data"`
+		Hora       string `xml:"hora"`
+		Descricao  string `xml:"descricao"`
+		Comentario string `xml:"comentario"`
+		Local      string `xml:"local"`
+		Codigo     string `xml:"codigo"`
+		Cidade     string `xml:"cidade"`
+		UF         string `xml:"uf"`
+	} `xml:"evento"`
+}
+
+// ValidarCodigoRastreio verifica se o código possui o formato de um
+// objeto dos Correios: duas letras, nove dígitos e duas letras (ex.:
+// "PY123456789BR").
+func ValidarCodigoRastreio(codigo string) bool {
+	codigo = strings.TrimSpace(codigo)
+	if len(codigo) != 13 {
+		return false
+	}
+	for i := 0; i < len(codigo); i++ {
+		ch := codigo[i]
+		if i < 2 || i > 10 {
+			if (ch < 'A' || ch > 'Z') && (ch < 'a' || ch > 'z') {
+				return false
+			}
+		} else if ch < '0' || ch > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Rastrear consulta os eventos de um objeto (ex.: "PY123456789BR")
+func Rastrear(ctx context.Context, codigo string) (*RastreioResult, error) {
+	return DefaultClient.Rastrear(ctx, codigo)
+}
+
+// Rastrear consulta os eventos de um objeto (ex.: "PY123456789BR")
+func (c *Client) Rastrear(ctx context.Context, codigo string) (*RastreioResult, error) {
+	if !ValidarCodigoRastreio(codigo) {
+		return nil, ErrCodigoRastreioInvalido
+	}
+	codigo = strings.ToUpper(strings.TrimSpace(codigo))
+	objs, err := c.rastrear(ctx, []string{codigo})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range objs {
+		if !strings.EqualFold(v.Numero, codigo) {
+			continue
+		}
+		if v.Erro != "" {
+			return nil, errors.New("correios: " + strings.TrimSpace(v.Erro))
+		}
+		return v.result()
+	}
+	return nil, ErrNoResults
+}
+
+// RastrearVarios consulta os eventos de vários objetos, agrupando-os em
+// requests de até RastreioMaxObjetos códigos
+func RastrearVarios(ctx context.Context, codigos []string) (map[string]*RastreioResult, error) {
+	return DefaultClient.RastrearVarios(ctx, codigos)
+}
+
+// RastrearVarios consulta os eventos de vários objetos, agrupando-os em
+// requests de até RastreioMaxObjetos códigos. O resultado é indexado pelo
+// código do objeto; os objetos que não puderam ser rastreados possuem o
+// campo Erro preenchido. Se um dos requests falhar, os resultados obtidos
+// até então são retornados junto com o erro.
+func (c *Client) RastrearVarios(ctx context.Context, codigos []string) (map[string]*RastreioResult, error) {
+	output := make(map[string]*RastreioResult)
+	norm := make([]string, 0, len(codigos))
+	for _, v := range codigos {
+		v = strings.ToUpper(strings.TrimSpace(v))
+		if _, ok := output[v]; ok || v == "" {
+			continue
+		}
+		if !ValidarCodigoRastreio(v) {
+			output[v] = &RastreioResult{
+				Codigo: v,
+				Erro:   ErrCodigoRastreioInvalido.Error(),
+			}
+			continue
+		}
+		output[v] = &RastreioResult{
+			Codigo: v,
+			Erro:   "objeto não retornado pelos Correios",
+		}
+		norm = append(norm, v)
+	}
+	for i := 0; i < len(norm); i += RastreioMaxObjetos {
+		j := i + RastreioMaxObjetos
+		if j > len(norm) {
+			j = len(norm)
+		}
+		objs, err := c.rastrear(ctx, norm[i:j])
+		if err != nil {
+			return output, err
+		}
+		for _, v := range objs {
+			codigo := strings.ToUpper(v.Numero)
+			if _, ok := output[codigo]; !ok {
+				continue
+			}
+			if v.Erro != "" {
+				output[codigo].Erro = strings.TrimSpace(v.Erro)
+				continue
+			}
+			r, err := v.result()
+			if err != nil {
+				output[codigo].Erro = err.Error()
+				continue
+			}
+			output[codigo] = r
+		}
+	}
+	return output, nil
+}
+
+// rastrear envia um request ao SRO com os códigos informados
+func (c *Client) rastrear(ctx context.Context, codigos []string) ([]sroObjeto, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	vals := url.Values{}
+	vals.Set("Usuario", RastreioUsuario)
+	vals.Set("Senha", RastreioSenha)
+	vals.Set("Tipo", "L")
+	vals.Set("Resultado", "T")
+	vals.Set("Objetos", strings.Join(codigos, ""))
+	rq0, err := http.NewRequestWithContext(ctx, http.MethodPost, c.rastreioEndpoint(), bytes.NewBufferString(vals.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	rq0.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	cresp, err := c.do(rq0)
+	if err != nil {
+		return nil, &TransporteError{Err: err}
+	}
+	defer cresp.Body.Close()
+	if cresp.StatusCode != http.StatusOK {
+		return nil, statusError(cresp)
+	}
+	p := xml.NewDecoder(cresp.Body)
+	p.CharsetReader = CharsetReader
+	vlov := struct {
+		XMLName string      `xml:"sroxml"`
+		Objetos []sroObjeto `xml:"objeto"`
+	}{}
+	if err := p.Decode(&vlov); err != nil {
+		return nil, &DecodeError{Err: fmt.Errorf("decode xml error: %w", err)}
+	}
+	return vlov.Objetos, nil
+}
+
+// result converte o objeto do SRO; datas inválidas resultam em erro (em vez
+// de eventos com a data zerada)
+func (o sroObjeto) result() (*RastreioResult, error) {
+	r := &RastreioResult{
+		Codigo:  o.Numero,
+		Eventos: make([]RastreioEvento, 0, len(o.Eventos)),
+	}
+	for _, v := range o.Eventos {
+		ev := RastreioEvento{
+			Tipo:       v.Tipo,
+			Status:     v.Status,
+			Descricao:  v.Descricao,
+			Comentario: v.Comentario,
+			Local:      v.Local,
+			CEP:        v.Codigo,
+			Cidade:     v.Cidade,
+			UF:         v.UF,
+		}
+		data, err := time.ParseInLocation("02/01/2006 15:04", v.Data+" "+v.Hora, fusoBrasilia)
+		if err != nil {
+			return nil, fmt.Errorf("correios: data inválida no evento %q: %w", v.Descricao, err)
+		}
+		ev.Data = data
+		r.Eventos = append(r.Eventos, ev)
+	}
+	return r, nil
+}
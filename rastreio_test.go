@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+)
+
+const sroXML = "<?xml version=\"1.0\" encoding=\"iso-8859-1\" ?>\n" +
+	"<sroxml><versao>1.0</versao><qtd>2</qtd><TipoPesquisa>Lista de Objetos</TipoPesquisa><TipoResultado>Todos os eventos</TipoResultado>" +
+	"<objeto><numero>PY123456789BR</numero>" +
+	"<evento><tipo>BDE</tipo><status>01</status><data>12/08/2020</data><hora>16:25</hora><descricao>Objeto entregue ao destinat\xe1rio</descricao>" +
+	"<recebedor></recebedor><documento></documento><comentario></comentario><local>CDD SAO JOSE DOS CAMPOS</local><codigo>12226970</codigo>" +
+	"<cidade>SAO JOSE DOS CAMPOS</cidade><uf>SP</uf></evento>" +
+	"<evento><tipo>PO</tipo><status>01</status><data>10/08/2020</data><hora>09:10</hora><descricao>Objeto postado</descricao>" +
+	"<local>AC CAMPINAS</local><codigo>13010971</codigo><cidade>CAMPINAS</cidade><uf>SP</uf></evento>" +
+	"</objeto>" +
+	"<objeto><numero>PY000000000BR</numero><erro>Objeto n\xe3o encontrado na base de dados dos Correios.</erro></objeto>" +
+	"</sroxml>"
+
+func withSROServer(t *testing.T) func() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "L", r.PostForm.Get("Tipo"))
+		w.Write([]byte(sroXML))
+	}))
+	prev := correios.RastreioEndpoint
+	correios.RastreioEndpoint = srv.URL
+	return func() {
+		correios.RastreioEndpoint = prev
+		srv.Close()
+	}
+}
+
+func TestRastrear(t *testing.T) {
+	defer withSROServer(t)()
+	r, err := correios.Rastrear(context.Background(), "py123456789br")
+	assert.NoError(t, err)
+	assert.Equal(t, "PY123456789BR", r.Codigo)
+	assert.Len(t, r.Eventos, 2)
+	assert.Equal(t, "Objeto entregue ao destinatário", r.Eventos[0].Descricao)
+	assert.Equal(t, "SP", r.Eventos[0].UF)
+	assert.True(t, time.Date(2020, 8, 12, 19, 25, 0, 0, time.UTC).Equal(r.Eventos[0].Data))
+
+	_, err = correios.Rastrear(context.Background(), "PY000000000BR")
+	assert.EqualError(t, err, "correios: Objeto não encontrado na base de dados dos Correios.")
+}
+
+func TestRastrearVarios(t *testing.T) {
+	defer withSROServer(t)()
+	rs, err := correios.RastrearVarios(context.Background(), []string{"PY123456789BR", "py000000000br", "PY111111111BR", "XX"})
+	assert.NoError(t, err)
+	assert.Len(t, rs, 4)
+	assert.Equal(t, correios.ErrCodigoRastreioInvalido.Error(), rs["XX"].Erro)
+	assert.Len(t, rs["PY123456789BR"].Eventos, 2)
+	assert.Empty(t, rs["PY123456789BR"].Erro)
+	assert.Equal(t, "Objeto não encontrado na base de dados dos Correios.", rs["PY000000000BR"].Erro)
+	assert.NotEmpty(t, rs["PY111111111BR"].Erro)
+}
+
+func TestRastrearDataInvalida(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Replace(sroXML, "<hora>16:25</hora>", "<hora>16h25</hora>", 1)))
+	}))
+	defer srv.Close()
+	c := &correios.Client{RastreioEndpoint: srv.URL}
+	_, err := c.Rastrear(context.Background(), "PY123456789BR")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "data inválida")
+
+	rs, err := c.RastrearVarios(context.Background(), []string{"PY123456789BR"})
+	assert.NoError(t, err)
+	assert.Contains(t, rs["PY123456789BR"].Erro, "data inválida")
+	assert.Empty(t, rs["PY123456789BR"].Eventos)
+}
+
+func TestRastrearErros(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/503" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("<html>"))
+	}))
+	defer srv.Close()
+	c := &correios.Client{RastreioEndpoint: srv.URL + "/503"}
+	_, err := c.Rastrear(context.Background(), "PY123456789BR")
+	var te *correios.TransporteError
+	if assert.True(t, errors.As(err, &te)) {
+		assert.Equal(t, http.StatusServiceUnavailable, te.StatusCode())
+	}
+	c.RastreioEndpoint = srv.URL
+	_, err = c.Rastrear(context.Background(), "PY123456789BR")
+	var de *correios.DecodeError
+	assert.True(t, errors.As(err, &de))
+}
+
+func TestValidarCodigoRastreio(t *testing.T) {
+	assert.True(t, correios.ValidarCodigoRastreio("PY123456789BR"))
+	assert.True(t, correios.ValidarCodigoRastreio(" py123456789br "))
+	assert.False(t, correios.ValidarCodigoRastreio("PY12345678BR"))
+	assert.False(t, correios.ValidarCodigoRastreio("P1123456789BR"))
+	assert.False(t, correios.ValidarCodigoRastreio("PY123456789B1"))
+	assert.False(t, correios.ValidarCodigoRastreio(""))
+	_, err := correios.Rastrear(context.Background(), "PY123BR")
+	assert.Equal(t, correios.ErrCodigoRastreioInvalido, err)
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryConfig configura novas tentativas (com backoff exponencial) em caso
+// de falhas transitórias: erros de rede, status 5xx, respostas que não podem
+// ser decodificadas, serviços com erro transitório (ver IsRetryable; trechos
+// sem cobertura e o código 010 não são repetidos) e respostas sem nenhum
+// serviço.
+type RetryConfig struct {
+	// MaxAttempts é o número máximo de tentativas (incluindo a primeira)
+	MaxAttempts int
+	// BaseDelay é o intervalo antes da segunda tentativa; o intervalo dobra
+	// a cada nova tentativa
+	BaseDelay time.Duration
+	// MaxDelay, se > 0, limita o intervalo entre as tentativas
+	MaxDelay time.Duration
+	// AceitarVazio faz com que respostas bem formadas, porém sem nenhum
+	// serviço (cServico), não sejam repetidas
+	AceitarVazio bool
+	// Jitter, entre 0 e 1, é a fração aleatória subtraída de cada intervalo
+	// (ex.: 0.5 aguarda entre 50% e 100% do intervalo), evitando que vários
+	// clientes repitam os requests ao mesmo tempo
+	Jitter float64
+}
+
+// Retry é a política de novas tentativas utilizada por CalcularFrete. Se
+// nil, nenhuma nova tentativa é feita.
+var Retry *RetryConfig
+
+func (rc *RetryConfig) attempts() int {
+	if rc == nil || rc.MaxAttempts < 1 {
+		return 1
+	}
+	return rc.MaxAttempts
+}
+
+// delay retorna o intervalo a aguardar após a tentativa n (1, 2, ...)
+func (rc *RetryConfig) delay(n int) time.Duration {
+	d := rc.BaseDelay
+	for i := 1; i < n; i++ {
+		d *= 2
+		if rc.MaxDelay > 0 && d >= rc.MaxDelay {
+			break
+		}
+	}
+	if rc.MaxDelay > 0 && d > rc.MaxDelay {
+		d = rc.MaxDelay
+	}
+	return d
+}
+
+// jitter aplica Jitter ao intervalo d
+func (rc *RetryConfig) jitter(d time.Duration) time.Duration {
+	j := rc.Jitter
+	if j <= 0 || d <= 0 {
+		return d
+	}
+	if j > 1 {
+		j = 1
+	}
+	return d - time.Duration(j*rand.Float64()*float64(d))
+}
+
+func (rc *RetryConfig) wait(ctx context.Context, n int) error {
+	t := time.NewTimer(rc.jitter(rc.delay(n)))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// httpStatusError é retornado quando o status da resposta não é 200
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *httpStatusError) Error() string {
+	return "http status: " + e.Status
+}
+
+// statusError retorna um *TransporteError p/ uma resposta com status != 200
+func statusError(resp *http.Response) error {
+	return &TransporteError{Err: &httpStatusError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+	}}
+}
+
+// retryFrete executa fn de acordo com a política rc
+func retryFrete(ctx context.Context, rc *RetryConfig, fn func() (*FreteResponse, error)) (*FreteResponse, error) {
+	for n := 1; ; n++ {
+		resp, err := fn()
+		if n >= rc.attempts() || !retryableFrete(ctx, rc, resp, err) {
+			return resp, err
+		}
+		if werr := rc.wait(ctx, n); werr != nil {
+			return nil, werr
+		}
+	}
+}
+
+// retryableFrete verifica se o resultado de um request indica uma falha
+// transitória
+func retryableFrete(ctx context.Context, rc *RetryConfig, resp *FreteResponse, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		var se *httpStatusError
+		if errors.As(err, &se) {
+			return se.StatusCode >= 500 || se.StatusCode == http.StatusTooManyRequests
+		}
+		var ce *cwsError
+		if errors.As(err, &ce) {
+			return ce.StatusCode >= 500 || ce.StatusCode == http.StatusTooManyRequests
+		}
+		return true
+	}
+	if resp == nil {
+		return false
+	}
+	// os Correios às vezes retornam um XML sem nenhum serviço
+	if len(resp.Servicos) == 0 {
+		return rc != nil && !rc.AceitarVazio
+	}
+	for _, v := range resp.Servicos {
+		if v.transitorio() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+// ServicoInfo descreve um tipo de serviço
+type ServicoInfo struct {
+	Tipo TipoServico `json:"codigo"`
+	// Nome é o nome comercial do serviço (ex.: "SEDEX 10 Varejo")
+	Nome string `json:"nome"`
+	// RequerContrato indica se o serviço exige CdEmpresa e DsSenha
+	RequerContrato bool `json:"requerContrato"`
+}
+
+// servicosConhecidos é a única tabela de nomes dos serviços (ver
+// TipoServico.String)
+var servicosConhecidos = []ServicoInfo{
+	{Tipo: SvcSEDEXVarejo, Nome: "SEDEX Varejo"},
+	{Tipo: SvcSEDEXACobrarVarejo, Nome: "SEDEX Varejo (a cobrar)"},
+	{Tipo: SvcSEDEX10Varejo, Nome: "SEDEX 10 Varejo"},
+	{Tipo: SvcSEDEXHojeVarejo, Nome: "SEDEX Hoje Varejo"},
+	{Tipo: SvcSEDEXComContrato, Nome: "SEDEX", RequerContrato: true},
+	{Tipo: SvcPACVarejo, Nome: "PAC Varejo"},
+	{Tipo: SvcPACComContrato, Nome: "PAC", RequerContrato: true},
+	{Tipo: SvcSEDEX12Varejo, Nome: "SEDEX 12 Varejo"},
+	{Tipo: SvcMiniEnvios, Nome: "Mini Envios", RequerContrato: true},
+}
+
+// ServicosConhecidos retorna todos os tipos de serviço definidos no pacote
+func ServicosConhecidos() []ServicoInfo {
+	out := make([]ServicoInfo, len(servicosConhecidos))
+	copy(out, servicosConhecidos)
+	return out
+}
+
+// ServicoNome retorna o nome comercial do serviço (ex.: "PAC Varejo"), o
+// mesmo de TipoServico.String. Para serviços desconhecidos, o próprio código
+// é retornado.
+func ServicoNome(svc TipoServico) string {
+	if info, ok := servicoInfo(svc); ok {
+		return info.Nome
+	}
+	return string(svc)
+}
+
+// RequerContrato informa se o serviço exige um contrato com os Correios
+// (CdEmpresa e DsSenha), ex.: SvcPACComContrato e SvcSEDEXComContrato.
+// Serviços desconhecidos retornam false.
+func RequerContrato(svc TipoServico) bool {
+	info, _ := servicoInfo(svc)
+	return info.RequerContrato
+}
+
+func servicoInfo(svc TipoServico) (ServicoInfo, bool) {
+	for _, v := range servicosConhecidos {
+		if v.Tipo == svc {
+			return v, true
+		}
+	}
+	return ServicoInfo{}, false
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServicosConhecidos(t *testing.T) {
+	list := correios.ServicosConhecidos()
+	assert.NotEmpty(t, list)
+	seen := make(map[correios.TipoServico]bool)
+	for _, v := range list {
+		assert.False(t, seen[v.Tipo], v.Tipo)
+		seen[v.Tipo] = true
+		assert.NotEmpty(t, v.Nome)
+	}
+	assert.Equal(t, "SEDEX 10 Varejo", correios.ServicoNome(correios.SvcSEDEX10Varejo))
+	assert.Equal(t, correios.SvcSEDEX12Varejo.String(), correios.ServicoNome(correios.SvcSEDEX12Varejo))
+	assert.Equal(t, "PAC", correios.ServicoNome(correios.SvcPACComContrato))
+	assert.Equal(t, "Mini Envios", correios.ServicoNome(correios.SvcMiniEnvios))
+	assert.Equal(t, "12345", correios.ServicoNome("12345"))
+	// a lista retornada é uma cópia
+	list[0].Nome = "x"
+	assert.NotEqual(t, "x", correios.ServicosConhecidos()[0].Nome)
+}
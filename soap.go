@@ -0,0 +1,134 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FreteSOAPEndpoint é o endpoint do serviço SOAP CalcPrecoPrazo, utilizado
+// quando Client.FreteSOAP é true. Aceita os mesmos parâmetros do
+// FreteEndpoint e costuma ser mais estável nos horários de pico.
+var FreteSOAPEndpoint = "https://ws.correios.com.br/calculador/CalcPrecoPrazo.asmx"
+
+const soapAction = "http://tempuri.org/CalcPrecoPrazo"
+
+// soapCalcPrecoPrazo é o corpo do request SOAP; todos os parâmetros são
+// obrigatórios, na ordem definida pelo WSDL
+type soapCalcPrecoPrazo struct {
+	XMLName            xml.Name `xml:"http://tempuri.org/ CalcPrecoPrazo"`
+	CdEmpresa          string   `xml:"nCdEmpresa"`
+	DsSenha            string   `xml:"sDsSenha"`
+	CdServico          string   `xml:"nCdServico"`
+	CepOrigem          string   `xml:"sCepOrigem"`
+	CepDestino         string   `xml:"sCepDestino"`
+	VlPeso             string   `xml:"nVlPeso"`
+	CdFormato          string   `xml:"nCdFormato"`
+	VlComprimento      string   `xml:"nVlComprimento"`
+	VlAltura           string   `xml:"nVlAltura"`
+	VlLargura          string   `xml:"nVlLargura"`
+	VlDiametro         string   `xml:"nVlDiametro"`
+	CdMaoPropria       string   `xml:"sCdMaoPropria"`
+	VlValorDeclarado   string   `xml:"nVlValorDeclarado"`
+	CdAvisoRecebimento string   `xml:"sCdAvisoRecebimento"`
+}
+
+type soapEnvelope struct {
+	XMLName xml.Name `xml:"http://schemas.xmlsoap.org/soap/envelope/ Envelope"`
+	Body    struct {
+		Content interface{}
+	} `xml:"http://schemas.xmlsoap.org/soap/envelope/ Body"`
+}
+
+// soapValor retorna v.Get(k) ou def, se vazio
+func soapValor(v url.Values, k, def string) string {
+	if s := v.Get(k); s != "" {
+		return s
+	}
+	return def
+}
+
+// soapRequest monta o envelope SOAP a partir dos parâmetros do FreteEndpoint
+func soapRequest(v url.Values) ([]byte, error) {
+	env := soapEnvelope{}
+	env.Body.Content = soapCalcPrecoPrazo{
+		CdEmpresa:          v.Get("nCdEmpresa"),
+		DsSenha:            v.Get("sDsSenha"),
+		CdServico:          v.Get("nCdServico"),
+		CepOrigem:          v.Get("sCepOrigem"),
+		CepDestino:         v.Get("sCepDestino"),
+		VlPeso:             soapValor(v, "nVlPeso", "0"),
+		CdFormato:          soapValor(v, "nCdFormato", "1"),
+		VlComprimento:      soapValor(v, "nVlComprimento", "0"),
+		VlAltura:           soapValor(v, "nVlAltura", "0"),
+		VlLargura:          soapValor(v, "nVlLargura", "0"),
+		VlDiametro:         soapValor(v, "nVlDiametro", "0"),
+		CdMaoPropria:       soapValor(v, "sCdMaoPropria", "N"),
+		VlValorDeclarado:   soapValor(v, "nVlValorDeclarado", "0"),
+		CdAvisoRecebimento: soapValor(v, "sCdAvisoRecebimento", "N"),
+	}
+	b, err := xml.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// requestFreteSOAP envia os parâmetros v ao FreteSOAPEndpoint e decodifica
+// a resposta
+func (c *Client) requestFreteSOAP(ctx context.Context, v url.Values) (*FreteResponse, error) {
+	body, err := soapRequest(v)
+	if err != nil {
+		return nil, err
+	}
+	rq0, _ := http.NewRequest(http.MethodPost, c.freteSOAPEndpoint(), bytes.NewReader(body))
+	rq0 = rq0.WithContext(ctx)
+	c.setFreteHeaders(rq0)
+	rq0.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	rq0.Header.Set("SOAPAction", `"`+soapAction+`"`)
+
+	raw, err := c.lerFrete(rq0, v)
+	if err != nil {
+		return nil, err
+	}
+	p := xml.NewDecoder(bytes.NewReader(raw))
+	p.CharsetReader = CharsetReader
+
+	resp := struct {
+		Values []servicoResp `xml:"Body>CalcPrecoPrazoResponse>CalcPrecoPrazoResult>Servicos>cServico"`
+	}{}
+	if err := p.Decode(&resp); err != nil {
+		return nil, &DecodeError{Err: fmt.Errorf("decode soap error: %w (body: %q)", err, snippet(raw, 256))}
+	}
+	// o serviço SOAP retorna o código como inteiro (ex.: 4014 p/ 04014)
+	for i := range resp.Values {
+		if cod := strings.TrimSpace(resp.Values[i].Codigo); cod != "" && len(cod) < 5 {
+			resp.Values[i].Codigo = strings.Repeat("0", 5-len(cod)) + cod
+		}
+	}
+	return freteResponse(resp.Values), nil
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+)
+
+const freteSOAP = `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+<soap:Body><CalcPrecoPrazoResponse xmlns="http://tempuri.org/"><CalcPrecoPrazoResult><Servicos>
+<cServico><Codigo>4014</Codigo><Valor>42,50</Valor><PrazoEntrega>3</PrazoEntrega><ValorMaoPropria>0,00</ValorMaoPropria><ValorAvisoRecebimento>0,00</ValorAvisoRecebimento><ValorValorDeclarado>0,00</ValorValorDeclarado><EntregaDomiciliar>S</EntregaDomiciliar><EntregaSabado>S</EntregaSabado><Erro>0</Erro><MsgErro /><ValorSemAdicionais>42,50</ValorSemAdicionais><obsFim /></cServico>
+<cServico><Codigo>4510</Codigo><Valor>0,00</Valor><PrazoEntrega>0</PrazoEntrega><ValorMaoPropria>0,00</ValorMaoPropria><ValorAvisoRecebimento>0,00</ValorAvisoRecebimento><ValorValorDeclarado>0,00</ValorValorDeclarado><EntregaDomiciliar /><EntregaSabado /><Erro>008</Erro><MsgErro>Serviço indisponível para o trecho informado.</MsgErro><ValorSemAdicionais>0,00</ValorSemAdicionais><obsFim /></cServico>
+</Servicos></CalcPrecoPrazoResult></CalcPrecoPrazoResponse></soap:Body></soap:Envelope>`
+
+func TestClientFreteSOAP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, `"http://tempuri.org/CalcPrecoPrazo"`, r.Header.Get("SOAPAction"))
+		var env struct {
+			Req struct {
+				CdEmpresa    string `xml:"nCdEmpresa"`
+				CdServico    string `xml:"nCdServico"`
+				CepOrigem    string `xml:"sCepOrigem"`
+				CdMaoPropria string `xml:"sCdMaoPropria"`
+				VlDiametro   string `xml:"nVlDiametro"`
+			} `xml:"Body>CalcPrecoPrazo"`
+		}
+		assert.NoError(t, xml.NewDecoder(r.Body).Decode(&env))
+		assert.Equal(t, "empresa", env.Req.CdEmpresa)
+		assert.Equal(t, "04014,04510", env.Req.CdServico)
+		assert.Equal(t, "01243000", env.Req.CepOrigem)
+		assert.Equal(t, "N", env.Req.CdMaoPropria)
+		assert.Equal(t, "0", env.Req.VlDiametro)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write([]byte(freteSOAP))
+	}))
+	defer srv.Close()
+	c := &correios.Client{
+		FreteEndpoint:     "http://127.0.0.1:1/nao-utilizado",
+		FreteSOAP:         true,
+		FreteSOAPEndpoint: srv.URL,
+	}
+	c.WithCredentials("empresa", "senha")
+	resp, err := c.CalcularFrete(context.Background(), correios.NewFreteRequest("01243000", "65299970"))
+	assert.NoError(t, err)
+	assert.Len(t, resp.Servicos, 2)
+	sedex := resp.Servicos[correios.SvcSEDEXVarejo]
+	assert.Equal(t, "42.5", sedex.Preco.String())
+	assert.Equal(t, 3, sedex.PrazoEntregaDias)
+	assert.True(t, sedex.EntregaSabado)
+	pac := resp.Servicos[correios.SvcPACVarejo]
+	if assert.NotNil(t, pac.Erro) {
+		assert.Equal(t, correios.ErrServicoIndisponivelTrecho2, pac.Erro.Codigo)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"sync"
+)
+
+// FreteResult é o resultado de um request de CalcularFreteStream
+type FreteResult struct {
+	Request  *FreteRequest
+	Response *FreteResponse
+	Err      error
+}
+
+// CalcularFreteStream calcula o frete de cada request recebido em reqs,
+// com concorrência limitada (ver Client.StreamConcurrency), emitindo os
+// resultados à medida que são concluídos (fora de ordem)
+func CalcularFreteStream(ctx context.Context, reqs <-chan *FreteRequest) <-chan FreteResult {
+	return DefaultClient.CalcularFreteStream(ctx, reqs)
+}
+
+// CalcularFreteStream funciona como a função CalcularFreteStream,
+// utilizando as configurações do Client.
+//
+// O canal retornado é fechado quando reqs for fechado e todos os requests
+// forem processados, ou quando ctx for cancelado (neste caso, os resultados
+// pendentes são descartados).
+func (c *Client) CalcularFreteStream(ctx context.Context, reqs <-chan *FreteRequest) <-chan FreteResult {
+	n := c.StreamConcurrency
+	if n <= 0 {
+		n = 4
+	}
+	out := make(chan FreteResult)
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var req *FreteRequest
+				var ok bool
+				select {
+				case <-ctx.Done():
+					return
+				case req, ok = <-reqs:
+					if !ok {
+						return
+					}
+				}
+				resp, err := c.CalcularFrete(ctx, req)
+				select {
+				case <-ctx.Done():
+					return
+				case out <- FreteResult{Request: req, Response: resp, Err: err}:
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
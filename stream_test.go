@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gabstv/correios"
+	"github.com/gabstv/correios/correiostest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalcularFreteStream(t *testing.T) {
+	srv := correiostest.NewServer()
+	defer srv.Close()
+	c := srv.Client()
+	c.StreamConcurrency = 2
+	reqs := make(chan *correios.FreteRequest)
+	go func() {
+		defer close(reqs)
+		for _, svc := range []correios.TipoServico{correios.SvcSEDEXVarejo, correios.SvcPACVarejo, "99999"} {
+			reqs <- correios.NewFreteRequest("01243000", "65299970").SetServicos(svc)
+		}
+	}()
+	precos := make(map[correios.TipoServico]string)
+	for res := range c.CalcularFreteStream(context.Background(), reqs) {
+		assert.NoError(t, res.Err)
+		svc := res.Request.Servicos[0]
+		precos[svc] = res.Response.Servicos[svc].Preco.String()
+	}
+	assert.Equal(t, map[correios.TipoServico]string{
+		correios.SvcSEDEXVarejo: "42.5",
+		correios.SvcPACVarejo:   "21.9",
+		"99999":                 "0",
+	}, precos)
+
+	// cancelamento: o canal é fechado mesmo que reqs continue aberto
+	ctx, cancel := context.WithCancel(context.Background())
+	open := make(chan *correios.FreteRequest)
+	out := c.CalcularFreteStream(ctx, open)
+	cancel()
+	select {
+	case _, ok := <-out:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("o canal não foi fechado")
+	}
+}
@@ -27,9 +27,13 @@ import (
 	"os"
 	"strings"
 	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
 )
 
-// FilterCEP removes non numbers from a CEP.
+// FilterCEP removes non numbers from a CEP, so "13056-535" becomes
+// "13056535". It does not check the length of the result; use ValidarCEP
+// to check if the input is a well-formed CEP.
 func FilterCEP(v string) string {
 	return strings.Map(func(r rune) rune {
 		if r >= '0' && r <= '9' {
@@ -39,8 +43,83 @@ func FilterCEP(v string) string {
 	}, v)
 }
 
+// ValidarCEP reports whether v is a well-formed brazilian ZIP code (CEP):
+// 8 digits, optionally formatted as "13056-535" or "13.056-535".
+func ValidarCEP(v string) bool {
+	v = strings.TrimSpace(v)
+	for _, r := range v {
+		if (r < '0' || r > '9') && r != '-' && r != '.' {
+			return false
+		}
+	}
+	return len(FilterCEP(v)) == 8
+}
+
+// FormatarCEP formats a CEP as "13056-535". The input may already be
+// formatted; if it is not a well-formed CEP (see ValidarCEP) it is returned
+// unchanged.
+func FormatarCEP(v string) string {
+	if !ValidarCEP(v) {
+		return v
+	}
+	d := FilterCEP(v)
+	return d[:5] + "-" + d[5:]
+}
+
+// LimparCEP removes the formatting of a CEP, so "13.056-535" becomes
+// "13056535". Unlike FilterCEP, if the input is not a well-formed CEP (see
+// ValidarCEP) it is returned unchanged.
+func LimparCEP(v string) string {
+	if !ValidarCEP(v) {
+		return v
+	}
+	return FilterCEP(v)
+}
+
+// snippet returns at most n bytes of b, used to add context to errors
+// without dumping the whole response.
+func snippet(b []byte, n int) string {
+	if len(b) <= n {
+		return string(b)
+	}
+	return string(b[:n]) + "..."
+}
+
+// isHTML reports whether b looks like an HTML page (e.g. an error page)
+// instead of a XML document.
+func isHTML(b []byte) bool {
+	b = bytes.TrimLeft(b, "\xef\xbb\xbf \t\r\n")
+	if len(b) > 16 {
+		b = b[:16]
+	}
+	b = bytes.ToLower(b)
+	return bytes.HasPrefix(b, []byte("<!doctype html")) || bytes.HasPrefix(b, []byte("<html"))
+}
+
+// parseDecimal parses a number in the brazilian format. Empty values are
+// parsed as zero.
+func parseDecimal(ds string) (decimal.Decimal, error) {
+	ds = fixWrongDecimals(ds)
+	if ds == "" {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(ds)
+}
+
+// fixWrongDecimals normalizes a number in the brazilian format ("1.234,56")
+// to the format accepted by decimal.NewFromString ("1234.56").
 func fixWrongDecimals(ds string) string {
-	return strings.Replace(ds, ",", ".", -1)
+	ds = strings.TrimSpace(ds)
+	if strings.Contains(ds, ",") {
+		// dots are thousand separators
+		ds = strings.Replace(ds, ".", "", -1)
+		return strings.Replace(ds, ",", ".", -1)
+	}
+	if strings.Count(ds, ".") > 1 {
+		// "1.234.567"
+		return strings.Replace(ds, ".", "", -1)
+	}
+	return ds
 }
 
 type CharsetISO88591er struct {
@@ -74,6 +153,49 @@ func (cs *CharsetISO88591er) Read(p []byte) (int, error) {
 	return 0, os.ErrInvalid
 }
 
+// cp1252 maps the 0x80–0x9F range of windows-1252; the bytes not defined
+// by windows-1252 are mapped to the same code point (like ISO-8859-1).
+var cp1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+type CharsetWindows1252er struct {
+	r   io.ByteReader
+	buf *bytes.Buffer
+}
+
+func NewCharsetWindows1252(r io.Reader) *CharsetWindows1252er {
+	buf := bytes.NewBuffer(make([]byte, 0, utf8.UTFMax))
+	return &CharsetWindows1252er{r.(io.ByteReader), buf}
+}
+
+func (cs *CharsetWindows1252er) ReadByte() (b byte, err error) {
+	// https://www.unicode.org/Public/MAPPINGS/VENDORS/MICSFT/WINDOWS/CP1252.TXT
+	if cs.buf.Len() <= 0 {
+		r, err := cs.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if r < utf8.RuneSelf {
+			return r, nil
+		}
+		if r >= 0x80 && r <= 0x9F {
+			cs.buf.WriteRune(cp1252[r-0x80])
+		} else {
+			cs.buf.WriteRune(rune(r))
+		}
+	}
+	return cs.buf.ReadByte()
+}
+
+func (cs *CharsetWindows1252er) Read(p []byte) (int, error) {
+	// Use ReadByte method.
+	return 0, os.ErrInvalid
+}
+
 func isCharset(charset string, names []string) bool {
 	charset = strings.ToLower(charset)
 	for _, n := range names {
@@ -104,6 +226,19 @@ func IsCharsetISO88591(charset string) bool {
 	return isCharset(charset, names)
 }
 
+func IsCharsetWindows1252(charset string) bool {
+	// http://www.iana.org/assignments/character-sets
+	names := []string{
+		// Name
+		"windows-1252",
+		// Aliases
+		"cswindows1252",
+		"cp1252",
+		"x-cp1252",
+	}
+	return isCharset(charset, names)
+}
+
 func IsCharsetUTF8(charset string) bool {
 	names := []string{
 		"UTF-8",
@@ -119,6 +254,8 @@ func CharsetReader(charset string, input io.Reader) (io.Reader, error) {
 		return input, nil
 	case IsCharsetISO88591(charset):
 		return NewCharsetISO88591(input), nil
+	case IsCharsetWindows1252(charset):
+		return NewCharsetWindows1252(input), nil
 	}
 	return nil, errors.New("CharsetReader: unexpected charset: " + charset)
 }
@@ -0,0 +1,54 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixWrongDecimals(t *testing.T) {
+	for in, out := range map[string]string{
+		"42,50":        "42.50",
+		"0,00":         "0.00",
+		"1.234,56":     "1234.56",
+		"1.234.567,89": "1234567.89",
+		"1.234.567":    "1234567",
+		"42.50":        "42.50",
+		"1234":         "1234",
+		" 21,90 ":      "21.90",
+		"":             "",
+	} {
+		assert.Equal(t, out, fixWrongDecimals(in), in)
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	rc := &RetryConfig{BaseDelay: 100 * time.Millisecond}
+	assert.Equal(t, 200*time.Millisecond, rc.jitter(rc.delay(2)))
+	rc.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := rc.jitter(rc.delay(2))
+		assert.True(t, d > 100*time.Millisecond && d <= 200*time.Millisecond, d)
+	}
+}
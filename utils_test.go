@@ -0,0 +1,53 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCharsetWindows1252(t *testing.T) {
+	src := append([]byte(`<?xml version="1.0" encoding="windows-1252"?><v>`),
+		0x93, 'S', 0xE3, 'o', ' ', 'P', 'a', 'u', 'l', 'o', 0x94, ' ', 0x96, ' ', 0x80, '1', 0x81)
+	src = append(src, []byte(`</v>`)...)
+	d := xml.NewDecoder(bytes.NewReader(src))
+	d.CharsetReader = correios.CharsetReader
+	var v string
+	assert.NoError(t, d.Decode(&v))
+	assert.Equal(t, "“São Paulo” – €1\u0081", v)
+	assert.True(t, correios.IsCharsetWindows1252("CP1252"))
+	assert.False(t, correios.IsCharsetWindows1252("ISO-8859-1"))
+}
+
+func TestFormatarCEP(t *testing.T) {
+	assert.Equal(t, "13056-535", correios.FormatarCEP("13056535"))
+	assert.Equal(t, "13056-535", correios.FormatarCEP("13056-535"))
+	assert.Equal(t, "13056-535", correios.FormatarCEP("13.056-535"))
+	assert.Equal(t, "1305653", correios.FormatarCEP("1305653"))
+	assert.Equal(t, "13056535", correios.LimparCEP("13.056-535"))
+	assert.Equal(t, "13056535", correios.LimparCEP("13056535"))
+	assert.Equal(t, "1305-653", correios.LimparCEP("1305-653"))
+}
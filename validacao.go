@@ -0,0 +1,352 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ValorDeclaradoMaximo é o maior valor declarado aceito pelos Correios (R$)
+var ValorDeclaradoMaximo = decimal.NewFromInt(10000)
+
+// ValorDeclaradoMinimo é o menor valor declarado aceito pelos Correios (R$),
+// utilizado por ClampValorDeclarado. O valor é reajustado periodicamente
+// pelos Correios.
+var ValorDeclaradoMinimo = decimal.NewFromFloat(24.5)
+
+// AjusteValorDeclarado descreve a alteração feita por ClampValorDeclarado
+type AjusteValorDeclarado struct {
+	Original decimal.Decimal `json:"original"`
+	Ajustado decimal.Decimal `json:"ajustado"`
+}
+
+// ClampValorDeclarado ajusta ValorDeclarado ao intervalo aceito pelos
+// Correios: valores acima de ValorDeclaradoMaximo são reduzidos ao máximo e
+// valores positivos abaixo de ValorDeclaradoMinimo são elevados ao mínimo.
+// Um valor zerado só é alterado (p/ o mínimo) se algum serviço exigir o
+// valor declarado (ex.: SEDEX a Cobrar). Retorna nil se r não foi alterado.
+func (r *FreteRequest) ClampValorDeclarado() *AjusteValorDeclarado {
+	v := r.ValorDeclarado
+	switch {
+	case v.GreaterThan(ValorDeclaradoMaximo):
+		v = ValorDeclaradoMaximo
+	case v.IsPositive() && v.LessThan(ValorDeclaradoMinimo):
+		v = ValorDeclaradoMinimo
+	case !v.IsPositive():
+		for _, svc := range r.Servicos {
+			if servicosValorDeclaradoObrigatorio[svc] {
+				v = ValorDeclaradoMinimo
+				break
+			}
+		}
+	}
+	if v.Equal(r.ValorDeclarado) {
+		return nil
+	}
+	ajuste := &AjusteValorDeclarado{
+		Original: r.ValorDeclarado,
+		Ajustado: v,
+	}
+	r.ValorDeclarado = v
+	return ajuste
+}
+
+// ValidacaoError é retornado por (*FreteRequest).Validate quando uma das
+// restrições dos Correios não é atendida. Codigo contém o erro que seria
+// retornado pela API dos Correios.
+type ValidacaoError struct {
+	Codigo TipoErro
+	Campo  string
+	// Aviso indica que o request provavelmente resultará em erro para algum
+	// serviço, mas pode ser enviado (ex.: mão própria em um serviço que não
+	// a oferece)
+	Aviso bool
+	// Soma e Excesso são preenchidos quando uma soma de dimensões supera o
+	// limite (ErrDimensoesSoma, ErrSomaDiametro, ErrComprimentoLargura120):
+	// Soma é o valor calculado (cm) e Excesso o quanto deve ser reduzido
+	Soma    decimal.Decimal
+	Excesso decimal.Decimal
+	// Desconhecidos são os códigos de serviço não reconhecidos
+	// (ErrTipoServicoInvalido, ver Client.PermitirServicosDesconhecidos)
+	Desconhecidos []TipoServico
+}
+
+// Error implementa a interface error
+func (e *ValidacaoError) Error() string {
+	if e.Aviso {
+		return "correios: aviso: " + e.Campo + ": " + e.Codigo.String()
+	}
+	if len(e.Desconhecidos) > 0 {
+		cods := make([]string, len(e.Desconhecidos))
+		for k, v := range e.Desconhecidos {
+			cods[k] = string(v)
+		}
+		return "correios: " + e.Campo + ": " + e.Codigo.String() + " (" + strings.Join(cods, ", ") + ")"
+	}
+	if e.Excesso.IsPositive() {
+		return "correios: " + e.Campo + ": " + e.Codigo.String() +
+			" (soma: " + e.Soma.String() + " cm, reduzir " + e.Excesso.String() + " cm)"
+	}
+	return "correios: " + e.Campo + ": " + e.Codigo.String()
+}
+
+// servicosValorDeclaradoObrigatorio são os serviços que exigem um valor
+// declarado (erro -7 dos Correios):
+//
+// SEDEX a Cobrar: o valor declarado é o valor a ser cobrado do destinatário
+var servicosValorDeclaradoObrigatorio = map[TipoServico]bool{
+	SvcSEDEXACobrarVarejo: true,
+}
+
+// Serviços que não oferecem mão própria ou aviso de recebimento, conforme
+// os erros -8 e -9 retornados pelos Correios:
+//
+// Mini Envios:  sem mão própria, sem aviso de recebimento
+// SEDEX Hoje:   sem mão própria
+var (
+	servicosSemMaoPropria = map[TipoServico]bool{
+		SvcMiniEnvios:      true,
+		SvcSEDEXHojeVarejo: true,
+	}
+	servicosSemAvisoRecebimento = map[TipoServico]bool{
+		SvcMiniEnvios: true,
+	}
+)
+
+func validacaoErr(campo string, codigo TipoErro) error {
+	return &ValidacaoError{
+		Codigo: codigo,
+		Campo:  campo,
+	}
+}
+
+// validacaoSomaErr retorna um *ValidacaoError com a soma das dimensões e o
+// excesso em relação ao limite
+func validacaoSomaErr(campo string, codigo TipoErro, soma decimal.Decimal, limite int64) error {
+	return &ValidacaoError{
+		Codigo:  codigo,
+		Campo:   campo,
+		Soma:    soma,
+		Excesso: soma.Sub(decimal.NewFromInt(limite)),
+	}
+}
+
+// validarCredenciais verifica se o código da empresa e a senha foram
+// informados juntos
+func validarCredenciais(cdEmpresa, dsSenha string) error {
+	switch {
+	case cdEmpresa != "" && dsSenha == "":
+		return validacaoErr("DsSenha", ErrCodigoOuSenha)
+	case cdEmpresa == "" && dsSenha != "":
+		return validacaoErr("CdEmpresa", ErrCodigoOuSenha)
+	}
+	return nil
+}
+
+// Validate verifica localmente as restrições de dimensões documentadas
+// pelos Correios, evitando um request que certamente resultaria em erro.
+// O erro retornado é um *ValidacaoError.
+//
+// Caixa/pacote:   comprimento 16–105, largura 11–105, altura 2–105, soma ≤ 200
+// Rolo/cilindro:  comprimento 18–105, diâmetro 5–91, comprimento + 2×diâmetro ≤ 200
+// Envelope:       comprimento 16–60, largura 11–60, comprimento + largura ≤ 120
+//
+// Os códigos de serviço devem constar em ServicosConhecidos (ver
+// Client.PermitirServicosDesconhecidos e Client.ValidarFrete).
+//
+// Quando uma soma é excedida, Soma e Excesso do *ValidacaoError informam o
+// valor calculado e quantos cm devem ser reduzidos.
+//
+// O valor declarado não deve superar ValorDeclaradoMaximo e é obrigatório
+// para SEDEX a Cobrar. CdEmpresa e DsSenha devem ser informados juntos e
+// são obrigatórios para os serviços com contrato (ver RequerContrato); as
+// credenciais do Client não são consideradas.
+//
+// Caso nenhuma restrição seja violada, MaoPropria e AvisoRecebimento são
+// verificados para cada serviço; se algum serviço não os oferecer, um
+// *ValidacaoError com Aviso == true é retornado.
+func (r *FreteRequest) Validate() error {
+	return r.validar(opcoesValidacao{})
+}
+
+// ValidarFrete valida o request com Validate, considerando as configurações
+// do DefaultClient
+func ValidarFrete(r *FreteRequest) error {
+	return DefaultClient.ValidarFrete(r)
+}
+
+// ValidarFrete valida o request com Validate, considerando as configurações
+// do Client: os serviços com contrato são aceitos sem CdEmpresa caso o
+// Client possua credenciais (ver WithCredentials) e os códigos de serviço
+// desconhecidos são aceitos se PermitirServicosDesconhecidos for true.
+func (c *Client) ValidarFrete(r *FreteRequest) error {
+	return r.validar(c.opcoesValidacao())
+}
+
+// opcoesValidacao ajusta as verificações de validar
+type opcoesValidacao struct {
+	// contratoClient indica que o Client possui credenciais, que serão
+	// utilizadas caso o request não informe CdEmpresa
+	contratoClient bool
+	// permitirDesconhecidos aceita códigos fora de ServicosConhecidos
+	permitirDesconhecidos bool
+}
+
+func (c *Client) opcoesValidacao() opcoesValidacao {
+	return opcoesValidacao{
+		contratoClient:        c.CdEmpresa != "",
+		permitirDesconhecidos: c.PermitirServicosDesconhecidos,
+	}
+}
+
+func (r *FreteRequest) validar(o opcoesValidacao) error {
+	if !ValidarCEP(r.CepOrigem) {
+		return validacaoErr("CepOrigem", ErrCepOrigemInvalido)
+	}
+	if !ValidarCEP(r.CepDestino) {
+		return validacaoErr("CepDestino", ErrCepDestinoInvalido)
+	}
+	if err := validarCredenciais(r.CdEmpresa, r.DsSenha); err != nil {
+		return err
+	}
+	if len(r.Servicos) == 0 {
+		return validacaoErr("Servicos", ErrTipoServicoInvalido)
+	}
+	if !o.permitirDesconhecidos {
+		var desconhecidos []TipoServico
+		for _, svc := range r.Servicos {
+			if _, ok := servicoInfo(svc); !ok {
+				desconhecidos = append(desconhecidos, svc)
+			}
+		}
+		if len(desconhecidos) > 0 {
+			return &ValidacaoError{Codigo: ErrTipoServicoInvalido, Campo: "Servicos", Desconhecidos: desconhecidos}
+		}
+	}
+	if r.CdEmpresa == "" && !o.contratoClient {
+		for _, svc := range r.Servicos {
+			if RequerContrato(svc) {
+				return validacaoErr("CdEmpresa", ErrSemContrato)
+			}
+		}
+	}
+	if r.ValorDeclarado.GreaterThan(ValorDeclaradoMaximo) {
+		return validacaoErr("ValorDeclarado", ErrValorDeclaradoAlto10k)
+	}
+	if !r.ValorDeclarado.IsPositive() {
+		for _, svc := range r.Servicos {
+			if servicosValorDeclaradoObrigatorio[svc] {
+				return validacaoErr("ValorDeclarado", ErrValorDeclaradoObrigatorio)
+			}
+		}
+	}
+	// as dimensões são verificadas p/ cada formato utilizado (ver Formatos)
+	validados := make(map[Formato]bool)
+	for _, svc := range r.Servicos {
+		formato := r.FormatoServico(svc)
+		if validados[formato] {
+			continue
+		}
+		validados[formato] = true
+		var err error
+		switch formato {
+		case FormatoRoloCilindro:
+			err = r.validateRoloCilindro()
+		case FormatoEnvelope:
+			err = r.validateEnvelope()
+		default:
+			err = r.validateCaixaPacote()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return r.validateAdicionais()
+}
+
+func (r *FreteRequest) validateAdicionais() error {
+	for _, svc := range r.Servicos {
+		if r.MaoPropria && servicosSemMaoPropria[svc] {
+			return &ValidacaoError{Codigo: ErrMaoPropriaIndisponivel, Campo: "MaoPropria", Aviso: true}
+		}
+		if r.AvisoRecebimento && servicosSemAvisoRecebimento[svc] {
+			return &ValidacaoError{Codigo: ErrAvisoRecebimentoIndisponivel, Campo: "AvisoRecebimento", Aviso: true}
+		}
+	}
+	return nil
+}
+
+func (r *FreteRequest) validateCaixaPacote() error {
+	switch {
+	case r.ComprimentoCm.LessThan(decimal.NewFromInt(16)):
+		return validacaoErr("ComprimentoCm", ErrComprimentoInferior)
+	case r.ComprimentoCm.GreaterThan(decimal.NewFromInt(105)):
+		return validacaoErr("ComprimentoCm", ErrComprimento105)
+	case r.LarguraCm.LessThan(decimal.NewFromInt(11)):
+		return validacaoErr("LarguraCm", ErrLarguraInferior)
+	case r.LarguraCm.GreaterThan(decimal.NewFromInt(105)):
+		return validacaoErr("LarguraCm", ErrLargura105)
+	case r.AlturaCm.LessThan(decimal.NewFromInt(2)):
+		return validacaoErr("AlturaCm", ErrAlturaInferior)
+	case r.AlturaCm.GreaterThan(decimal.NewFromInt(105)):
+		return validacaoErr("AlturaCm", ErrAltura105)
+	}
+	if soma := r.ComprimentoCm.Add(r.LarguraCm).Add(r.AlturaCm); soma.GreaterThan(decimal.NewFromInt(200)) {
+		return validacaoSomaErr("ComprimentoCm+LarguraCm+AlturaCm", ErrDimensoesSoma, soma, 200)
+	}
+	return nil
+}
+
+func (r *FreteRequest) validateRoloCilindro() error {
+	switch {
+	case r.ComprimentoCm.LessThan(decimal.NewFromInt(18)):
+		return validacaoErr("ComprimentoCm", ErrComprimento18)
+	case r.ComprimentoCm.GreaterThan(decimal.NewFromInt(105)):
+		return validacaoErr("ComprimentoCm", ErrComprimento4)
+	case r.DiametroCm.LessThan(decimal.NewFromInt(5)):
+		return validacaoErr("DiametroCm", ErrDiametro5)
+	case r.DiametroCm.GreaterThan(decimal.NewFromInt(91)):
+		return validacaoErr("DiametroCm", ErrDiametro91)
+	}
+	if soma := r.ComprimentoCm.Add(r.DiametroCm.Mul(decimal.NewFromInt(2))); soma.GreaterThan(decimal.NewFromInt(200)) {
+		return validacaoSomaErr("ComprimentoCm+DiametroCm", ErrSomaDiametro, soma, 200)
+	}
+	return nil
+}
+
+func (r *FreteRequest) validateEnvelope() error {
+	switch {
+	case r.ComprimentoCm.LessThan(decimal.NewFromInt(16)):
+		return validacaoErr("ComprimentoCm", ErrComprimento16)
+	case r.ComprimentoCm.GreaterThan(decimal.NewFromInt(60)):
+		return validacaoErr("ComprimentoCm", ErrComprimento60)
+	case r.LarguraCm.LessThan(decimal.NewFromInt(11)):
+		return validacaoErr("LarguraCm", ErrLarguraInferior2)
+	case r.LarguraCm.GreaterThan(decimal.NewFromInt(60)):
+		return validacaoErr("LarguraCm", ErrLarguraSuperior60)
+	}
+	if soma := r.ComprimentoCm.Add(r.LarguraCm); soma.GreaterThan(decimal.NewFromInt(120)) {
+		return validacaoSomaErr("ComprimentoCm+LarguraCm", ErrComprimentoLargura120, soma, 120)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gabstv/correios"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// codigo retorna o Codigo de um *ValidacaoError (ou 0)
+func codigo(err error) correios.TipoErro {
+	var ve *correios.ValidacaoError
+	if errors.As(err, &ve) {
+		return ve.Codigo
+	}
+	return 0
+}
+
+func TestValidate(t *testing.T) {
+	r := correios.NewFreteRequest("01243-000", "65299970")
+	assert.NoError(t, r.Validate())
+
+	r = correios.NewFreteRequest("0124300", "65299970")
+	assert.Equal(t, correios.ErrCepOrigemInvalido, codigo(r.Validate()))
+
+	r = correios.NewFreteRequest("01243000", "65299970")
+	r.ComprimentoCm = decimal.NewFromInt(15)
+	assert.Equal(t, correios.ErrComprimentoInferior, codigo(r.Validate()))
+
+	r = correios.NewFreteRequest("01243000", "65299970")
+	r.ComprimentoCm = decimal.NewFromInt(100)
+	r.LarguraCm = decimal.NewFromInt(60)
+	r.AlturaCm = decimal.NewFromInt(50)
+	err := r.Validate()
+	assert.Equal(t, correios.ErrDimensoesSoma, codigo(err))
+	var ve *correios.ValidacaoError
+	if assert.True(t, errors.As(err, &ve)) {
+		assert.Equal(t, "210", ve.Soma.String())
+		assert.Equal(t, "10", ve.Excesso.String())
+	}
+	assert.Contains(t, err.Error(), "(soma: 210 cm, reduzir 10 cm)")
+
+	// rolo: largura e altura são ignoradas
+	r = correios.NewFreteRequest("01243000", "65299970")
+	r.Formato = correios.FormatoRoloCilindro
+	r.ComprimentoCm = decimal.NewFromInt(60)
+	r.LarguraCm = decimal.Zero
+	r.AlturaCm = decimal.Zero
+	r.DiametroCm = decimal.NewFromInt(10)
+	assert.NoError(t, r.Validate())
+	r.DiametroCm = decimal.NewFromInt(80)
+	err = r.Validate()
+	assert.Equal(t, correios.ErrSomaDiametro, codigo(err))
+	if assert.True(t, errors.As(err, &ve)) {
+		assert.Equal(t, "220", ve.Soma.String())
+		assert.Equal(t, "20", ve.Excesso.String())
+	}
+
+	r = correios.NewFreteRequest("01243000", "65299970")
+	r.Formato = correios.FormatoEnvelope
+	r.ComprimentoCm = decimal.NewFromInt(61)
+	assert.Equal(t, correios.ErrComprimento60, codigo(r.Validate()))
+}
+
+func TestValidateAdicionais(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970").
+		SetServicos(correios.SvcPACVarejo, correios.SvcMiniEnvios)
+	r.CdEmpresa, r.DsSenha = "08082650", "n5f9t8"
+	assert.NoError(t, r.Validate())
+	r.MaoPropria = true
+	err := r.Validate()
+	var ve *correios.ValidacaoError
+	if assert.True(t, errors.As(err, &ve)) {
+		assert.True(t, ve.Aviso)
+		assert.Equal(t, correios.ErrMaoPropriaIndisponivel, ve.Codigo)
+		assert.Equal(t, "correios: aviso: MaoPropria: Serviço de Mão Própria não disponível", err.Error())
+	}
+	r.MaoPropria = false
+	r.AvisoRecebimento = true
+	assert.Equal(t, correios.ErrAvisoRecebimentoIndisponivel, codigo(r.Validate()))
+	r.SetServicos(correios.SvcPACVarejo)
+	assert.NoError(t, r.Validate())
+}
+
+func TestValidateContrato(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970").
+		SetServicos(correios.SvcPACVarejo, correios.SvcSEDEXComContrato)
+	err := r.Validate()
+	assert.Equal(t, correios.ErrSemContrato, codigo(err))
+	assert.Contains(t, err.Error(), "CdEmpresa")
+	r.CdEmpresa, r.DsSenha = "08082650", "n5f9t8"
+	assert.NoError(t, r.Validate())
+
+	assert.True(t, correios.RequerContrato(correios.SvcPACComContrato))
+	assert.False(t, correios.RequerContrato(correios.SvcPACVarejo))
+	assert.False(t, correios.RequerContrato("12345"))
+}
+
+func TestValidateValorDeclaradoObrigatorio(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970").
+		SetServicos(correios.SvcSEDEXVarejo, correios.SvcSEDEXACobrarVarejo)
+	assert.Equal(t, correios.ErrValorDeclaradoObrigatorio, codigo(r.Validate()))
+	r.SetValorDeclaradoFloat(150)
+	assert.NoError(t, r.Validate())
+	r.SetValorDeclaradoFloat(0).SetServicos(correios.SvcSEDEXVarejo)
+	assert.NoError(t, r.Validate())
+}
+
+func TestClampValorDeclarado(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970")
+	assert.Nil(t, r.ClampValorDeclarado())
+	r.SetValorDeclaradoFloat(150)
+	assert.Nil(t, r.ClampValorDeclarado())
+
+	r.SetValorDeclaradoFloat(10)
+	a := r.ClampValorDeclarado()
+	if assert.NotNil(t, a) {
+		assert.Equal(t, "10", a.Original.String())
+		assert.Equal(t, "24.5", a.Ajustado.String())
+	}
+	assert.Equal(t, "24.5", r.ValorDeclarado.String())
+
+	r.SetValorDeclaradoFloat(12000)
+	assert.NotNil(t, r.ClampValorDeclarado())
+	assert.Equal(t, "10000", r.ValorDeclarado.String())
+
+	r.SetValorDeclaradoFloat(0).SetServicos(correios.SvcSEDEXACobrarVarejo)
+	assert.NotNil(t, r.ClampValorDeclarado())
+	assert.Equal(t, "24.5", r.ValorDeclarado.String())
+}
+
+func TestValidateServicosDesconhecidos(t *testing.T) {
+	r := correios.NewFreteRequest("01243000", "65299970").
+		SetServicos(correios.SvcSEDEXVarejo, "12345", "4014")
+	err := r.Validate()
+	var ve *correios.ValidacaoError
+	if assert.True(t, errors.As(err, &ve)) {
+		assert.Equal(t, correios.ErrTipoServicoInvalido, ve.Codigo)
+		assert.Equal(t, []correios.TipoServico{"12345", "4014"}, ve.Desconhecidos)
+		assert.Equal(t, "correios: Servicos: Código de serviço inválido (12345, 4014)", err.Error())
+	}
+	c := &correios.Client{}
+	assert.Equal(t, err, c.ValidarFrete(r))
+	c.PermitirServicosDesconhecidos = true
+	assert.NoError(t, c.ValidarFrete(r))
+	// Validate não considera as configurações do Client
+	assert.Error(t, r.Validate())
+}
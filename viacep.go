@@ -0,0 +1,82 @@
+// Copyright (c) 2021 Gabriel Ochsenhofer
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package correios
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ViaCEPURL is the base URL of the ViaCEP API, used by ConsultaCEP when
+// Client.ViaCEPFallback is set (unless Client.ViaCEPURL is set).
+var ViaCEPURL = "https://viacep.com.br/ws/"
+
+// viaCEPResult is the JSON returned by ViaCEP. Not found CEPs return
+// {"erro": true} (or "true" in newer versions).
+type viaCEPResult struct {
+	CEP        string      `json:"cep"`
+	Logradouro string      `json:"logradouro"`
+	Bairro     string      `json:"bairro"`
+	Localidade string      `json:"localidade"`
+	UF         string      `json:"uf"`
+	Unidade    string      `json:"unidade"`
+	Erro       interface{} `json:"erro"`
+}
+
+// consultaViaCEP looks up a (filtered) CEP using ViaCEP.
+func (c *Client) consultaViaCEP(ctx context.Context, cep string) (*CEPResult, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	u := strings.TrimRight(c.viaCEPURL(), "/") + "/" + cep + "/json/"
+	rq0, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	cresp, err := c.do(rq0)
+	if err != nil {
+		return nil, err
+	}
+	defer cresp.Body.Close()
+	if cresp.StatusCode != http.StatusOK {
+		return nil, &TransporteError{Err: &httpStatusError{
+			StatusCode: cresp.StatusCode,
+			Status:     cresp.Status,
+		}}
+	}
+	v := &viaCEPResult{}
+	if err := json.NewDecoder(cresp.Body).Decode(v); err != nil {
+		return nil, fmt.Errorf("decode json error: %w", err)
+	}
+	if v.Erro == true || v.Erro == "true" {
+		return nil, ErrNoResults
+	}
+	return &CEPResult{
+		CEP:         FilterCEP(v.CEP),
+		UF:          v.UF,
+		Cidade:      v.Localidade,
+		Bairro:      v.Bairro,
+		Logradouro:  v.Logradouro,
+		NomeUnidade: v.Unidade,
+	}, nil
+}